@@ -11,12 +11,17 @@ import (
 
 	"tg-handler/conf"
 	"tg-handler/history"
+	"tg-handler/langdetect"
 	"tg-handler/logging"
 	"tg-handler/memory"
 	"tg-handler/messaging"
 	"tg-handler/model"
 	"tg-handler/names"
+	"tg-handler/outbox"
+	"tg-handler/outgoing"
 	"tg-handler/prompts"
+	"tg-handler/reflection"
+	"tg-handler/speech"
 	"tg-handler/translator"
 )
 
@@ -25,20 +30,39 @@ var (
 	errAuthFailed     = errors.New("authorization failed")
 	errChatNotAllowed = errors.New("chat is not allowed")
 	errMsgMalformed   = errors.New("message malformed")
+	errSendFailed     = errors.New("sending reply failed")
+	errReplyDropped   = errors.New("reply dropped from outbox")
+	errMsgDuplicate   = errors.New("message already handled")
+	errNoMessage      = errors.New("update has no message")
 )
 
 type Bot struct {
 	API         *tg.BotAPI
+	Sender      *outgoing.Sender // Rate limited outgoing requests
 	ID          int64
 	UserName    string
 	FirstName   string
-	Conf        *conf.BotConf            // Bot config
-	Settings    *conf.BotSettings        // Init config
-	ChatQueues  history.SharedChatQueues // Preinit, shared, r-only
-	UpdSignalCh chan<- any               // Signal update end
-	History     *history.SafeBotHistory  // Chat histories
-	Contacts    *history.SafeBotContacts // Chat agnostic contacts
+	Conf        *conf.BotConf             // Bot config
+	Chains      *model.Chains             // Model chains per stage
+	Settings    *conf.BotSettings         // Init config
+	ChatQueues  *history.SharedChatQueues // Preinit, shared
+	UpdSignalCh chan<- any                // Signal update end
+	History     *history.SafeBotHistory   // Chat histories
+	Contacts    *history.SafeBotContacts  // Chat agnostic contacts
+	Updates     *history.SafeBotUpdates   // Processed updates
+	Reflections *reflection.Queue         // Pending reflections
+	Outbox      *outbox.Outbox            // Undelivered replies
+	Translators *translator.Translators   // Per chat translators
+	Describer   *model.Describer          // Image descriptions, nil if off
+	Transcriber speech.Transcriber        // Voice transcripts, nil if off
+	Synthesizer speech.Synthesizer        // Voice replies, nil if off
+	Detector    *langdetect.Detector      // Shared language detector
 	wg          *sync.WaitGroup
+	files       string   // File download URL template
+	maxImage    int      // Image size limit in bytes
+	maxVoice    int      // Voice size limit in bytes
+	topics      sync.Map // Forum topic names by chat key
+	lanes       *chatLanes
 	logger      *logging.Logger
 }
 
@@ -46,12 +70,14 @@ func New(
 	apiKey string,
 	iConf *conf.InitConf,
 	h *history.History,
+	scheduler *model.Scheduler,
+	detector *langdetect.Detector,
 	updSignalCh chan<- any,
 	wg *sync.WaitGroup,
 	logger *logging.Logger,
 ) *Bot {
-	// Authorize as bot
-	bot, err := tg.NewBotAPI(apiKey)
+	// Authorize as bot through configured endpoint
+	bot, fileEndpoint, err := newBotAPI(apiKey, &iConf.TelegramSettings)
 	if err != nil {
 		logger.With(logging.ApiKey(apiKey)).
 			Panic("not authorized", logging.Err(errAuthFailed))
@@ -64,9 +90,9 @@ func New(
 	logger = logger.With(logging.BotName(userName))
 	defer logger.Info("authorized")
 
-	// Get history and contacts
+	// Get history, contacts and processed updates
 	data := h.Bots.Get(userName)
-	history, contacts := data.History, data.Contacts
+	history, contacts, updates := data.History, data.Contacts, data.Updates
 
 	// Get config path
 	confPath := filepath.Join(
@@ -75,32 +101,103 @@ func New(
 	// Get config
 	botConf := conf.MustLoadBotConf(
 		confPath,
-		&iConf.BotSettings.DefaultOptions,
+		&iConf.BotSettings,
 		logger,
 	)
 
+	// Get model chains
+	chains := model.MustNewChains(botConf, scheduler, userName, logger)
+
+	// Get translators
+	translators := translator.New(
+		&botConf.Translation,
+		model.NewTranslator(
+			chains, botConf, iConf.BotSettings.PromptTemplates.Translate,
+			logger,
+		),
+	)
+
+	// Get image describer
+	describer := model.NewDescriber(
+		chains, botConf, iConf.BotSettings.PromptTemplates.Describe,
+		logger,
+	)
+
+	// Get speech clients
+	var (
+		speechSettings = &iConf.SpeechSettings
+		transcriber    = speech.NewTranscriber(&speechSettings.Transcription)
+		synthesizer    = speech.NewSynthesizer(&speechSettings.Synthesis)
+	)
+
+	// Get media size limits
+	maxImage := iConf.MediaSettings.MaxImageSize
+	if maxImage == 0 {
+		maxImage = defaultMaxImageSize
+	}
+	maxVoice := iConf.MediaSettings.MaxVoiceSize
+	if maxVoice == 0 {
+		maxVoice = defaultMaxVoiceSize
+	}
+
+	// Get reflection queue
+	reflections := reflection.LoadQueue(
+		getReflectionsPath(&iConf.Paths, userName), logger,
+	)
+
+	// Get outbox
+	outbox := outbox.Load(
+		getOutboxPath(&iConf.Paths, userName),
+		&iConf.SendingSettings, logger,
+	)
+
 	return &Bot{
 		API:         bot,
+		Sender:      outgoing.New(bot, &iConf.SendingSettings),
 		ID:          bot.Self.ID,
 		UserName:    userName,
 		FirstName:   bot.Self.FirstName,
 		Conf:        botConf,
+		Chains:      chains,
 		Settings:    &iConf.BotSettings,
 		ChatQueues:  h.SharedChatQueues,
 		UpdSignalCh: updSignalCh,
 		History:     history,
 		Contacts:    contacts,
+		Updates:     updates,
+		Reflections: reflections,
+		Outbox:      outbox,
+		Translators: translators,
+		Describer:   describer,
+		Transcriber: transcriber,
+		Synthesizer: synthesizer,
+		Detector:    detector,
 		wg:          wg,
+		files:       fileEndpoint,
+		maxImage:    maxImage,
+		maxVoice:    maxVoice,
+		lanes:       newChatLanes(),
 		logger:      logger,
 	}
 }
 
 // Starts bot
 func (bot *Bot) Start(ctx context.Context) {
-	// Prepare updates channel
-	u := tg.NewUpdate(0)
-	u.Timeout = 30
-	updates := bot.API.GetUpdatesChan(u)
+	// Poll updates in background, resume after processed ones
+	updates := make(chan update, pollLimit)
+	go bot.poll(ctx, bot.Updates.Offset(), updates)
+
+	// Reflect in background until context DONE
+	bot.wg.Go(func() {
+		bot.Reflections.Run(
+			ctx, bot.Settings.ReflectionSettings.Workers, bot.reflect,
+		)
+	})
+
+	// Deliver outbox in background until context DONE
+	bot.wg.Go(func() {
+		bot.runOutbox(ctx)
+	})
 
 	// Handle updates until channel CLOSED or context DONE
 	defer bot.logger.Info("shut down gracefully")
@@ -120,45 +217,137 @@ func (bot *Bot) Start(ctx context.Context) {
 }
 
 // Handles update
-func (bot *Bot) handleUpdate(ctx context.Context, upd tg.Update) {
+func (bot *Bot) handleUpdate(ctx context.Context, upd update) {
 	const errMsg = "update not handled"
 	logger := bot.logger
 
 	logger.Info("got update")
 
+	// Keep update in progress until message is handled
+	// for it to be redelivered after crash, skipped ones are done
+	bot.Updates.Begin(upd.UpdateID)
+	skip := func() {
+		bot.Updates.Finish(upd.UpdateID, 0, 0)
+	}
+
+	// Skip updates without message (channel posts, edits, membership)
+	if upd.Message == nil {
+		logger.Debug(errMsg, logging.Err(errNoMessage))
+		skip()
+		return
+	}
+
+	// Get forum topic name before service messages are skipped
+	topic := bot.topicName(upd)
+
 	// Get message info and check if valid
 	msgInfo, err := bot.getMessageInfo(upd.Message)
 	if err != nil {
 		logger.Error(errMsg, logging.Err(
 			fmt.Errorf("%w: %v", errMsgMalformed, err),
 		))
+		skip()
+		return
+	}
+
+	// Keep non-text messages without caption in chat queue
+	// unless they trigger
+	isBare := msgInfo.IsDescribed && msgInfo.Text() == ""
+	if isBare && !bot.Settings.Descriptions.Triggering {
+		msgInfo.IsTriggering = false
+	}
+
+	// Skip message redelivered after restart
+	if bot.Updates.IsHandled(msgInfo.Chat.ID, msgInfo.ID) {
+		logger.Error(errMsg, logging.Err(errMsgDuplicate))
+		skip()
 		return
 	}
 
 	// Get chat info and check if allowed
-	chatInfo := bot.getChatInfo(msgInfo)
+	chatInfo := bot.getChatInfo(msgInfo, upd.threadID, topic)
 	logger = logger.With(logging.ChatID(chatInfo.ID))
+	logger = logger.With(logging.ThreadID(chatInfo.ThreadID))
 	logger = logger.With(logging.UserName(msgInfo.Sender()))
 	if !chatInfo.IsAllowed {
 		logger.Error(errMsg, logging.Err(errChatNotAllowed))
+		skip()
 		return
 	}
 
+	// Mark update done once message is queued or its reply persisted
+	done := func() {
+		bot.Updates.Finish(upd.UpdateID, chatInfo.ID, msgInfo.ID)
+	}
+
+	// Perceive media, then route message in chat order
+	key := history.ChatKey{ChatID: chatInfo.ID, ThreadID: chatInfo.ThreadID}
+	bot.inOrder(key, func() {
+		images := bot.perceive(ctx, upd.Message, chatInfo.LastMsg, logger)
+		bot.routeMessage(ctx, chatInfo, images, done, logger)
+	})
+}
+
+// Looks at image and listens to voice of message,
+// gets images for reply
+func (bot *Bot) perceive(
+	ctx context.Context,
+	msg *tg.Message,
+	msgInfo *messaging.MessageInfo,
+	logger *logging.Logger,
+) []string {
+	if bot.hearsVoice(msgInfo) {
+		bot.listenTo(ctx, msg, msgInfo, logger)
+	}
+	if bot.seesImage(msgInfo) {
+		return bot.lookAt(ctx, msg, msgInfo, logger)
+	}
+	return nil
+}
+
+// Routes message with its images in chat context,
+// calls done once it is handled
+func (bot *Bot) routeMessage(
+	ctx context.Context,
+	chatInfo *messaging.ChatInfo,
+	images []string,
+	done func(),
+	logger *logging.Logger,
+) {
 	// Safe to chat queue if not triggered
 	if !chatInfo.LastMsg.IsTriggering {
-		chatInfo.History.AddToChatQueue(
-			chatInfo.LastMsg, logger,
-		)
+		if bot.recordOnce(chatInfo, logger) {
+			chatInfo.History.AddToChatQueue(
+				chatInfo.LastMsg, logger,
+			)
+		}
+		bot.detectLanguages(chatInfo, logger)
+		done()
 		return
 	}
 
-	bot.handleMessage(ctx, chatInfo, logger)
+	bot.handleMessage(ctx, chatInfo, images, done, logger)
+}
+
+// Marks message recorded before adding it to history,
+// reports false if message redelivered after crash already is
+func (bot *Bot) recordOnce(
+	chatInfo *messaging.ChatInfo, logger *logging.Logger,
+) bool {
+	if bot.Updates.Record(chatInfo.ID, chatInfo.LastMsg.ID) {
+		return true
+	}
+	logger.Debug("message already in history")
+	return false
 }
 
-// Handles message in chat context
+// Handles message with its images in chat context,
+// calls done once reply is persisted or failed for good
 func (bot *Bot) handleMessage(
 	ctx context.Context,
 	chatInfo *messaging.ChatInfo,
+	images []string,
+	done func(),
 	logger *logging.Logger,
 ) {
 	const errMsg = "message not handled"
@@ -166,88 +355,113 @@ func (bot *Bot) handleMessage(
 	logger.Info("got message")
 
 	// Add new message to history
-	chatInfo.History.AddToBoth(chatInfo.LastMsg, logger)
+	if bot.recordOnce(chatInfo, logger) {
+		chatInfo.History.AddToBoth(chatInfo.LastMsg, logger)
+	}
+	bot.detectLanguages(chatInfo, logger)
+
+	// Create model
+	model := bot.newModel(
+		chatInfo.History, chatInfo.LastMsg,
+		chatInfo.LastMsg.Sender(), chatInfo.Title, logger,
+	)
+	model.Images = images
+
+	bot.wg.Go(func() {
+		// Generate reply as bot,
+		// leave message to redelivery if interrupted by shutdown
+		text, err := bot.reply(ctx, model, chatInfo)
+		if err != nil {
+			logger.Error(errMsg, logging.Err(err))
+			if ctx.Err() == nil {
+				done()
+			}
+			return
+		}
+
+		// Persist reply before sending, deliver it
+		entry := &outbox.Entry{
+			ChatID:    chatInfo.ID,
+			ThreadID:  chatInfo.ThreadID,
+			ChatTitle: chatInfo.Title,
+			ReplyToID: chatInfo.LastMsg.ID,
+			User:      chatInfo.LastMsg.Sender(),
+			UserLine:  chatInfo.LastMsg.Line(),
+			Text:      text,
+			Format:    bot.Conf.Main.Format,
+		}
+		bot.Outbox.Add(entry)
+		done()
+		bot.deliver(ctx, entry, logger)
+	})
+}
 
+// Creates model for chat history with user
+func (bot *Bot) newModel(
+	chatHistory *history.ChatHistory,
+	lineChain memory.LineChain,
+	user string,
+	chatTitle string,
+	logger *logging.Logger,
+) *model.Model {
 	// Create names
-	names := names.New(bot.FirstName, chatInfo.LastMsg.Sender())
+	names := names.New(bot.FirstName, bot.UserName, user)
 
 	// Create memory
 	memory := memory.New(
-		chatInfo.History, bot.Contacts,
-		chatInfo.LastMsg, &bot.Settings.MemoryLimits, logger,
+		chatHistory, bot.Contacts,
+		lineChain, &bot.Settings.MemoryLimits, logger,
 	)
 
 	// Get prompts
 	prompts := prompts.New(
 		&bot.Settings.PromptTemplates,
-		memory, names, chatInfo.Title,
-		bot.Conf.Main.CandidateNum,
+		memory, names, chatTitle,
+		bot.replyLanguage(user, chatHistory),
 	)
 
 	// Create model
-	model := model.New(
-		bot.Conf, prompts, memory, names, chatInfo.Title, logger,
+	return model.New(
+		bot.Chains, bot.Conf, prompts, memory, names,
+		chatTitle, logger,
 	)
+}
 
-	bot.wg.Go(func() {
-		// Reply as bot with valid info
-		replyInfo, err := bot.reply(ctx, model, chatInfo)
-		if err != nil {
-			logger.Error(errMsg, logging.Err(err))
-			return
-		}
-
-		// Add reply to history
-		chatInfo.History.AddToBoth(replyInfo, logger)
-
-		// Reflect on reply as model
-		err = model.Reflect(
-			ctx, chatInfo.LastMsg.Sender(), replyInfo,
-		)
-		if err != nil {
-			logger.Error(errMsg, logging.Err(err))
-			return
-		}
-
-		// Send update signal
-		bot.UpdSignalCh <- struct{}{}
-	})
+// Signals history update unless context done
+func (bot *Bot) signalUpdate(ctx context.Context) {
+	select {
+	case bot.UpdSignalCh <- struct{}{}:
+	case <-ctx.Done():
+	}
 }
 
-// Replies to message in chat, return reply message info
+// Generates reply to message in chat, gets its text
 func (bot *Bot) reply(
 	ctx context.Context,
 	model *model.Model,
 	chatInfo *messaging.ChatInfo,
-) (*messaging.MessageInfo, error) {
-	var replyInfo *messaging.MessageInfo
-	var err error
-
+) (string, error) {
 	// Type until reply
 	typingCtx, cancel := context.WithCancel(ctx)
-	go messaging.Type(typingCtx, bot.API, chatInfo, model.Logger)
+	go messaging.Type(typingCtx, bot.Sender, chatInfo, model.Logger)
 	defer cancel()
 
 	// Reply as model
 	text, err := model.Reply(ctx)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// Translate via translator
-	text, err = translator.Translate(text)
-	if err != nil {
-		return nil, err
-	}
-
-	// Reply as bot
-	reply := messaging.Reply(bot.API, chatInfo, text, model.Logger)
-	replyInfo, err = bot.getMessageInfo(reply)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", errMsgMalformed, err)
+	// Keep reply already generated in user language
+	if bot.Conf.Main.LanguageMode == conf.LanguageReply {
+		return text, nil
 	}
 
-	return replyInfo, nil
+	// Translate for chat, untranslated on failure
+	to := bot.translationTarget(model.Names.User, chatInfo.History)
+	return bot.Translators.Translate(
+		ctx, chatInfo.ID, text, to, model.Logger,
+	), nil
 }
 
 // Gets message info for bot
@@ -258,18 +472,22 @@ func (bot *Bot) getMessageInfo(
 		bot.API, msg,
 		bot.getAdminDetector(),
 		bot.getReplyDetector(),
+		bot.getDiscussionDetector(),
 		bot.getMentionDetector(),
 		bot.getMentionModifier(),
+		bot.getMessageDescriber(),
 		1,
 	)
 }
 
-// Gets chat info for bot
+// Gets chat info for bot in forum topic
 func (bot *Bot) getChatInfo(
 	msgInfo *messaging.MessageInfo,
+	threadID int,
+	topic string,
 ) *messaging.ChatInfo {
 	return messaging.NewChatInfo(
-		msgInfo,
+		msgInfo, threadID, topic,
 		bot.History,
 		bot.ChatQueues, // Shared chat queues for public chats
 		bot.getChatValidator(),
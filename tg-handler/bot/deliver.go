@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/conf"
+	"tg-handler/history"
+	"tg-handler/logging"
+	"tg-handler/messaging"
+	"tg-handler/outbox"
+	"tg-handler/outgoing"
+	"tg-handler/reflection"
+)
+
+// Delivers pending outbox entries on start (left by crash or
+// failures) and then with interval until context done
+func (bot *Bot) runOutbox(ctx context.Context) {
+	t := time.NewTicker(bot.Outbox.Interval())
+	defer t.Stop()
+
+	for {
+		for _, e := range bot.Outbox.Take() {
+			logger := bot.logger.With(
+				logging.ChatID(e.ChatID),
+				logging.ThreadID(e.ThreadID),
+				logging.UserName(e.User),
+			)
+			bot.deliver(ctx, e, logger)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Delivers outbox entry, records reply on success.
+// Failed entry stays in outbox until attempts run out,
+// undeliverable one is dropped. Partially delivered one
+// records the sent part and keeps the rest.
+func (bot *Bot) deliver(
+	ctx context.Context, e *outbox.Entry, logger *logging.Logger,
+) {
+	const (
+		errMsg     = "reply not delivered, will retry"
+		droppedMsg = "reply not delivered, dropped"
+	)
+
+	// Reply as bot
+	reply, err := bot.say(ctx, e, logger)
+	if err == nil {
+		bot.Outbox.Done(e)
+		bot.record(ctx, e, reply, logger)
+		return
+	}
+
+	// Record sent part, keep the rest
+	var partial *messaging.PartialError
+	if errors.As(err, &partial) {
+		bot.record(ctx, e, reply, logger)
+		bot.Outbox.Keep(e, partial.Rest)
+	}
+	err = fmt.Errorf("%w: %w", errSendFailed, err)
+
+	// Stay persisted for restart on context done
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Drop undeliverable
+	if outgoing.IsPermanent(err) {
+		bot.Outbox.Done(e)
+		logger.Error(droppedMsg, logging.Err(err))
+		return
+	}
+
+	// Retry later unless attempts run out
+	attempts, dropped := bot.Outbox.Fail(e)
+	if dropped {
+		err = fmt.Errorf("%w: %v", errReplyDropped, err)
+		logger.Error(droppedMsg, logging.Err(err))
+		return
+	}
+	logger.Error(errMsg, logging.Attempt(attempts), logging.Err(err))
+}
+
+// Adds delivered reply to history and queues reflection on it
+func (bot *Bot) record(
+	ctx context.Context, e *outbox.Entry, reply *tg.Message,
+	logger *logging.Logger,
+) {
+	const errMsg = "reply not recorded"
+
+	// Get reply message info
+	replyInfo, err := bot.getMessageInfo(reply)
+	if err != nil {
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errMsgMalformed, err),
+		))
+		return
+	}
+
+	// Add reply to history and save it right away
+	key := history.ChatKey{ChatID: e.ChatID, ThreadID: e.ThreadID}
+	chatHistory, _ := bot.History.Get(key, bot.ChatQueues.Get(key))
+	chatHistory.AddToBoth(replyInfo, logger)
+	bot.signalUpdate(ctx)
+
+	// Queue reflection on reply
+	bot.Reflections.Push(reflection.NewJob(
+		e.ChatID, e.ThreadID, e.ChatTitle, e.User, e.UserLine,
+		replyInfo.Line(),
+	))
+}
+
+// Gets outbox path for bot,
+// next to history if directory not set
+func getOutboxPath(paths *conf.Paths, userName string) string {
+	dir := paths.OutboxDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(paths.History), "outbox")
+	}
+	return filepath.Join(dir, userName+".json")
+}
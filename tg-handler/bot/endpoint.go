@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/conf"
+)
+
+// Bot API endpoint templates relative to base URL
+const (
+	apiEndpointT  = "%s/bot%%s/%%s"
+	fileEndpointT = "%s/file/bot%%s/%%s"
+)
+
+// Download errors
+var (
+	errDownloadFailed = errors.New("file download failed")
+	errFileTooLarge   = errors.New("file exceeds size limit")
+)
+
+// Authorizes as bot through configured Bot API endpoint and proxy
+func newBotAPI(
+	apiKey string, settings *conf.TelegramSettings,
+) (*tg.BotAPI, string, error) {
+	endpoint := settings.For(keyBotID(apiKey))
+	base := strings.TrimSuffix(endpoint.APIURL, "/")
+
+	// Use proxy if set, environment one otherwise,
+	// keeping default timeouts and connection limits
+	client := &http.Client{}
+	if endpoint.Proxy != "" {
+		proxyURL, err := url.Parse(endpoint.Proxy)
+		if err != nil {
+			return nil, "", err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		client.Transport = transport
+	}
+
+	api, err := tg.NewBotAPIWithClient(
+		apiKey, fmt.Sprintf(apiEndpointT, base), client,
+	)
+	return api, fmt.Sprintf(fileEndpointT, base), err
+}
+
+// Gets bot ID from API key prefix, 0 if malformed
+func keyBotID(apiKey string) int64 {
+	prefix, _, _ := strings.Cut(apiKey, ":")
+	id, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// Gets download URL of file path through bot endpoint
+func (bot *Bot) FileURL(filePath string) string {
+	return fmt.Sprintf(bot.files, bot.API.Token, filePath)
+}
+
+// Downloads file through bot endpoint within size limit.
+// Local Bot API server (--local) gets absolute paths,
+// such files are read from disk shared with the server.
+func (bot *Bot) download(
+	ctx context.Context, fileID string, maxSize int,
+) ([]byte, error) {
+	// Get file path
+	file, err := bot.API.GetFile(tg.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDownloadFailed, err)
+	}
+
+	// Read local server file
+	if filepath.IsAbs(file.FilePath) {
+		f, err := os.Open(file.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errDownloadFailed, err)
+		}
+		defer f.Close()
+		return readLimited(f, maxSize)
+	}
+
+	// Download file with bot client
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, bot.FileURL(file.FilePath), nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDownloadFailed, err)
+	}
+	resp, err := bot.API.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDownloadFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", errDownloadFailed, resp.Status)
+	}
+
+	return readLimited(resp.Body, maxSize)
+}
+
+// Reads file within size limit
+func readLimited(r io.Reader, maxSize int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDownloadFailed, err)
+	}
+	if len(data) > maxSize {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/messaging"
 )
 
 // Gets admin identifier for bot
@@ -27,9 +29,9 @@ func (bot *Bot) getReplyDetector() func(msg *tg.Message) bool {
 
 		// Get replied message
 		repliedMsg := msg.ReplyToMessage
-		// Try to get replied user ID
+		// Try to get replied user ID, none for sender chats
 		var repliedUserID int64
-		if repliedMsg != nil {
+		if repliedMsg != nil && repliedMsg.From != nil {
 			repliedUserID = repliedMsg.From.ID
 		}
 
@@ -42,6 +44,17 @@ func (bot *Bot) getReplyDetector() func(msg *tg.Message) bool {
 	}
 }
 
+// Gets discussion identifier for bot
+func (bot *Bot) getDiscussionDetector() func(*tg.Message) bool {
+	participates := bot.Conf.Main.Discussions
+
+	// Identifies if message is channel post forwarded
+	// to discussion group and bot comments them
+	return func(msg *tg.Message) bool {
+		return participates && msg.IsAutomaticForward
+	}
+}
+
 // Gets mention identifier for bot
 func (bot *Bot) getMentionDetector() func(string) bool {
 	// Identifies if text contains bot's @username
@@ -58,15 +71,25 @@ func (bot *Bot) getMentionModifier() func(string) string {
 	}
 }
 
+// Gets non-text message describer for bot
+func (bot *Bot) getMessageDescriber() func(*tg.Message) string {
+	settings := &bot.Settings.Descriptions
+
+	// Renders message of enabled kind to placeholder
+	return func(msg *tg.Message) string {
+		return messaging.Describe(msg, settings)
+	}
+}
+
 // Gets chat validator for bot
-func (bot *Bot) getChatValidator() func(int64) bool {
-	allowedCIDs := bot.Settings.AllowedChats.IDs
+func (bot *Bot) getChatValidator() func(int64, int) bool {
+	allowed := &bot.Settings.AllowedChats
 
-	// Identifies if chat has allowed ID
-	return func(cid int64) bool {
-		for _, allowedCID := range allowedCIDs {
+	// Identifies if chat has allowed ID and topic
+	return func(cid int64, threadID int) bool {
+		for _, allowedCID := range allowed.IDs {
 			if cid == allowedCID {
-				return true
+				return allowed.IsTopicAllowed(cid, threadID)
 			}
 		}
 		return false
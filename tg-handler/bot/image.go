@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/logging"
+	"tg-handler/messaging"
+)
+
+// Image constants
+const defaultMaxImageSize = 5 << 20 // 5MB
+
+// Image errors
+var (
+	errNoImage = errors.New("no image within size limit")
+)
+
+// Reports if bot looks at image of message:
+// describes it or replies to it
+func (bot *Bot) seesImage(msgInfo *messaging.MessageInfo) bool {
+	if !msgInfo.HasImage {
+		return false
+	}
+	return bot.Describer != nil || bot.Chains.Response.Vision()
+}
+
+// Looks at image of message: describes it to message line
+// if describer is set, gets it base64 encoded for reply
+// if response models accept images.
+// Failures are logged, message keeps image placeholder.
+func (bot *Bot) lookAt(
+	ctx context.Context,
+	msg *tg.Message,
+	msgInfo *messaging.MessageInfo,
+	logger *logging.Logger,
+) []string {
+	image, err := bot.downloadImage(ctx, msg)
+	if err != nil {
+		logger.Error("image not seen", logging.Err(err))
+		return nil
+	}
+
+	// Describe image for history
+	if bot.Describer != nil {
+		description, err := bot.Describer.Describe(ctx, image, msgInfo.Text())
+		if err != nil {
+			logger.Error("image not described", logging.Err(err))
+		} else {
+			msgInfo.DescribeImage(description)
+		}
+	}
+
+	if !bot.Chains.Response.Vision() {
+		return nil
+	}
+	return []string{image}
+}
+
+// Downloads image of message within size limit, base64 encoded
+func (bot *Bot) downloadImage(
+	ctx context.Context, msg *tg.Message,
+) (string, error) {
+	fileID := imageFileID(msg, bot.maxImage)
+	if fileID == "" {
+		return "", errNoImage
+	}
+
+	data, err := bot.download(ctx, fileID, bot.maxImage)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Gets file ID of the largest photo size or image document
+// within size limit, empty if none.
+// Unknown sizes are let through to be limited on download.
+func imageFileID(msg *tg.Message, maxSize int) string {
+	// Photo sizes go in ascending order
+	var fileID string
+	for _, photo := range msg.Photo {
+		if photo.FileSize <= maxSize {
+			fileID = photo.FileID
+		}
+	}
+	if fileID != "" {
+		return fileID
+	}
+
+	doc := msg.Document
+	if doc != nil && messaging.HasImage(msg) && doc.FileSize <= maxSize {
+		return doc.FileID
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"sync"
+
+	"tg-handler/history"
+)
+
+// Serial task queues per chat keeping message order
+// while media of some messages are perceived
+type chatLanes struct {
+	mu     sync.Mutex
+	queues map[history.ChatKey][]func() // Present while lane runs
+}
+
+func newChatLanes() *chatLanes {
+	return &chatLanes{
+		queues: make(map[history.ChatKey][]func()),
+	}
+}
+
+// Runs task after previous tasks of chat in background
+func (bot *Bot) inOrder(key history.ChatKey, task func()) {
+	lanes := bot.lanes
+
+	lanes.mu.Lock()
+	defer lanes.mu.Unlock()
+
+	queue, running := lanes.queues[key]
+	lanes.queues[key] = append(queue, task)
+	if running {
+		return
+	}
+
+	bot.wg.Go(func() {
+		lanes.run(key)
+	})
+}
+
+// Runs tasks of chat until none left
+func (cl *chatLanes) run(key history.ChatKey) {
+	for {
+		cl.mu.Lock()
+		queue := cl.queues[key]
+		if len(queue) == 0 {
+			delete(cl.queues, key)
+			cl.mu.Unlock()
+			return
+		}
+		task := queue[0]
+		cl.queues[key] = queue[1:]
+		cl.mu.Unlock()
+
+		task()
+	}
+}
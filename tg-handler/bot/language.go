@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"tg-handler/conf"
+	"tg-handler/history"
+	"tg-handler/langdetect"
+	"tg-handler/logging"
+	"tg-handler/messaging"
+)
+
+// Detects language of last message for its sender
+// and dominant language of chat queue if enabled
+func (bot *Bot) detectLanguages(
+	chatInfo *messaging.ChatInfo, logger *logging.Logger,
+) {
+	if bot.Conf.Main.LanguageMode == conf.LanguageOff {
+		return
+	}
+
+	// Detect sender language
+	var (
+		lastMsg = chatInfo.LastMsg
+		sender  = lastMsg.Sender()
+	)
+	language := bot.Detector.Detect(lastMsg.Text())
+	if language == "" {
+		return
+	}
+	bot.Contacts.SetLanguage(sender, language)
+	logger.Debug("user language detected", logging.Language(language))
+
+	// Update dominant chat language from recent lines incrementally
+	dominant := chatInfo.History.ChatQueue.AddLanguage(
+		lastMsg.ID, language, bot.Settings.MemoryLimits.ChatQueue,
+	)
+	logger.Debug("chat language detected", logging.Language(dominant))
+}
+
+// Gets language code of user: contact one, chat one,
+// English if unknown
+func (bot *Bot) userLanguage(
+	user string, chatHistory *history.ChatHistory,
+) string {
+	if language := bot.Contacts.Get(user).Language; language != "" {
+		return language
+	}
+	if language := chatHistory.ChatQueue.GetLanguage(); language != "" {
+		return language
+	}
+	return langdetect.English
+}
+
+// Gets language name model replies in for language mode
+func (bot *Bot) replyLanguage(
+	user string, chatHistory *history.ChatHistory,
+) string {
+	if bot.Conf.Main.LanguageMode != conf.LanguageReply {
+		return langdetect.Name(langdetect.English)
+	}
+	return langdetect.Name(bot.userLanguage(user, chatHistory))
+}
+
+// Gets translation target for language mode, empty for chat one
+func (bot *Bot) translationTarget(
+	user string, chatHistory *history.ChatHistory,
+) string {
+	if bot.Conf.Main.LanguageMode != conf.LanguageTranslate {
+		return ""
+	}
+	return bot.userLanguage(user, chatHistory)
+}
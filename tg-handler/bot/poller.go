@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/history"
+	"tg-handler/logging"
+)
+
+// Poller constants
+const (
+	pollTimeout  = 30 // Seconds, long polling
+	pollLimit    = 100
+	pollInterval = 3 * time.Second // After failure
+)
+
+// Poller errors
+var (
+	errPollFailed   = errors.New("getting updates failed")
+	errDecodeFailed = errors.New("decoding update failed")
+)
+
+// Update with fields missing in Telegram library
+type update struct {
+	tg.Update
+	threadID int    // Forum topic, 0 if none
+	topic    string // Forum topic name, empty if not carried
+}
+
+// Raw update fields missing in Telegram library
+type rawUpdate struct {
+	Message *rawMessage `json:"message"`
+}
+
+type rawMessage struct {
+	MessageID         int         `json:"message_id"`
+	MessageThreadID   int         `json:"message_thread_id"`
+	IsTopicMessage    bool        `json:"is_topic_message"`
+	ForumTopicCreated *forumTopic `json:"forum_topic_created"`
+	ForumTopicEdited  *forumTopic `json:"forum_topic_edited"`
+	ReplyToMessage    *rawMessage `json:"reply_to_message"`
+}
+
+type forumTopic struct {
+	Name string `json:"name"`
+}
+
+// Polls updates from offset until context done, then closes channel.
+// Replaces library polling to keep forum topic fields.
+func (bot *Bot) poll(
+	ctx context.Context, offset int, updates chan<- update,
+) {
+	const errMsg = "polling failed"
+	defer close(updates)
+
+	for ctx.Err() == nil {
+		// Get raw updates
+		params := make(tg.Params)
+		params.AddNonZero("offset", offset)
+		params.AddNonZero("limit", pollLimit)
+		params.AddNonZero("timeout", pollTimeout)
+		resp, err := bot.API.MakeRequest("getUpdates", params)
+
+		var raws []json.RawMessage
+		if err == nil {
+			err = json.Unmarshal(resp.Result, &raws)
+		}
+		if err != nil {
+			bot.logger.Error(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errPollFailed, err),
+			))
+			select {
+			case <-time.After(pollInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		// Decode and pass updates, skipping malformed ones
+		for _, raw := range raws {
+			upd, err := decodeUpdate(raw)
+			offset = max(offset, upd.UpdateID+1)
+			if err != nil {
+				bot.logger.Error(errMsg, logging.Err(
+					fmt.Errorf("%w: %v", errDecodeFailed, err),
+				))
+				continue
+			}
+
+			select {
+			case updates <- upd:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Decodes update with forum topic fields. Implicit reply
+// to topic creation carried by topic messages is dropped.
+func decodeUpdate(raw json.RawMessage) (update, error) {
+	var (
+		upd   update
+		extra rawUpdate
+	)
+	err := json.Unmarshal(raw, &upd.Update)
+	if err == nil {
+		err = json.Unmarshal(raw, &extra)
+	}
+	if err != nil {
+		return upd, err
+	}
+
+	// Skip messages out of topics, reply threads of
+	// ordinary supergroups carry thread ID as well
+	msg := extra.Message
+	if msg == nil || upd.Message == nil || !msg.IsTopicMessage {
+		return upd, nil
+	}
+	upd.threadID = msg.MessageThreadID
+
+	// Get topic name from topic creation or edit
+	root := msg.ReplyToMessage
+	if root != nil && root.MessageID == msg.MessageThreadID {
+		if root.ForumTopicCreated != nil {
+			upd.topic = root.ForumTopicCreated.Name
+		}
+		upd.Message.ReplyToMessage = nil
+	}
+	if msg.ForumTopicEdited != nil && msg.ForumTopicEdited.Name != "" {
+		upd.topic = msg.ForumTopicEdited.Name
+	}
+
+	return upd, nil
+}
+
+// Gets forum topic name of update, remembering carried ones
+// as replies within topic do not carry it
+func (bot *Bot) topicName(upd update) string {
+	if upd.threadID == 0 || upd.Message == nil || upd.Message.Chat == nil {
+		return ""
+	}
+	key := history.ChatKey{
+		ChatID: upd.Message.Chat.ID, ThreadID: upd.threadID,
+	}
+
+	// Remember carried name
+	if upd.topic != "" {
+		bot.topics.Store(key, upd.topic)
+		return upd.topic
+	}
+
+	// Get remembered name
+	name, _ := bot.topics.Load(key)
+	topic, _ := name.(string)
+	return topic
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+
+	"tg-handler/conf"
+	"tg-handler/history"
+	"tg-handler/logging"
+	"tg-handler/reflection"
+)
+
+// Reflects on user after batched replies as model
+func (bot *Bot) reflect(ctx context.Context, job *reflection.Job) error {
+	logger := bot.logger.With(
+		logging.ChatID(job.ChatID),
+		logging.ThreadID(job.ThreadID),
+		logging.UserName(job.User),
+	)
+
+	// Get chat history (shared chat queue for public chats)
+	key := history.ChatKey{ChatID: job.ChatID, ThreadID: job.ThreadID}
+	chatHistory, _ := bot.History.Get(key, bot.ChatQueues.Get(key))
+
+	// Create model with memory unrolled from last reply
+	model := bot.newModel(
+		chatHistory, job, job.User, job.ChatTitle, logger,
+	)
+
+	// Reflect as model
+	err := model.Reflect(ctx, job.User, job.Replies())
+	if err != nil {
+		return err
+	}
+
+	// Send update signal
+	bot.signalUpdate(ctx)
+	return nil
+}
+
+// Gets reflection queue path for bot,
+// next to history if directory not set
+func getReflectionsPath(paths *conf.Paths, userName string) string {
+	dir := paths.ReflectionsDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(paths.History), "reflections")
+	}
+	return filepath.Join(dir, userName+".json")
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/logging"
+	"tg-handler/messaging"
+	"tg-handler/outbox"
+	"tg-handler/outgoing"
+)
+
+// Voice constants
+const defaultMaxVoiceSize = 20 << 20 // 20MB, Bot API download limit
+
+// Voice errors
+var (
+	errNoVoice      = errors.New("no voice within size limit")
+	errSpeechFailed = errors.New("speech synthesis failed")
+)
+
+// Reports if bot listens to voice of message
+func (bot *Bot) hearsVoice(msgInfo *messaging.MessageInfo) bool {
+	return msgInfo.HasVoice && bot.Transcriber != nil
+}
+
+// Listens to voice note or audio of message,
+// transcribing it to message text and line.
+// Failures are logged, message keeps voice placeholder.
+func (bot *Bot) listenTo(
+	ctx context.Context,
+	msg *tg.Message,
+	msgInfo *messaging.MessageInfo,
+	logger *logging.Logger,
+) {
+	const errMsg = "voice not heard"
+
+	// Download voice within size limit
+	fileID, name := voiceFile(msg, bot.maxVoice)
+	if fileID == "" {
+		logger.Error(errMsg, logging.Err(errNoVoice))
+		return
+	}
+	audio, err := bot.download(ctx, fileID, bot.maxVoice)
+	if err != nil {
+		logger.Error(errMsg, logging.Err(err))
+		return
+	}
+
+	// Transcribe voice to message
+	transcript, err := bot.Transcriber.Transcribe(ctx, audio, name)
+	if err != nil {
+		logger.Error(errMsg, logging.Err(err))
+		return
+	}
+	msgInfo.Transcribe(transcript)
+}
+
+// Gets file ID and name of voice note or audio within size limit,
+// empty if none
+func voiceFile(msg *tg.Message, maxSize int) (string, string) {
+	voice, audio := msg.Voice, msg.Audio
+
+	switch {
+	case voice != nil && voice.FileSize <= maxSize:
+		return voice.FileID, "voice.ogg"
+	case audio != nil && audio.FileSize <= maxSize:
+		if audio.FileName == "" {
+			return audio.FileID, "audio"
+		}
+		return audio.FileID, audio.FileName
+	default:
+		return "", ""
+	}
+}
+
+// Reports if bot replies by voice
+func (bot *Bot) speaks() bool {
+	return bot.Conf.Main.Voice != "" && bot.Synthesizer != nil
+}
+
+// Replies with outbox entry by voice if bot speaks,
+// by text otherwise or if voice reply failed
+func (bot *Bot) say(
+	ctx context.Context, e *outbox.Entry, logger *logging.Logger,
+) (*tg.Message, error) {
+	if bot.speaks() && len(e.Rest) == 0 {
+		reply, err := bot.speak(ctx, e, logger)
+		if err == nil || outgoing.IsPermanent(err) || ctx.Err() != nil {
+			return reply, err
+		}
+		logger.Error("replying by text", logging.Err(err))
+	}
+
+	return messaging.Reply(ctx, bot.Sender, e, logger)
+}
+
+// Replies with outbox entry by voice
+func (bot *Bot) speak(
+	ctx context.Context, e *outbox.Entry, logger *logging.Logger,
+) (*tg.Message, error) {
+	audio, err := bot.Synthesizer.Synthesize(
+		ctx, e.Text, bot.Conf.Main.Voice,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSpeechFailed, err)
+	}
+
+	return messaging.ReplyVoice(ctx, bot.Sender, e, audio, logger)
+}
@@ -3,6 +3,8 @@ package carma
 import (
 	"errors"
 	"fmt"
+
+	"tg-handler/schema"
 )
 
 const (
@@ -56,6 +58,23 @@ func (u Update) String() string {
 	return UpdateTag[u]
 }
 
+// Structured carma update output
+type Verdict struct {
+	Update string `json:"update" enum:"-,=,+"`
+}
+
+// Schema for structured carma update output
+var Schema = schema.MustOf(Verdict{})
+
+// Parses structured output, falls back to free text
+func ParseUpdate(s string) (Update, error) {
+	var verdict Verdict
+	if err := schema.Decode(s, &verdict); err != nil {
+		return NewUpdate(s)
+	}
+	return NewUpdate(verdict.Update)
+}
+
 // From string
 func NewUpdate(s string) (Update, error) {
 	switch s {
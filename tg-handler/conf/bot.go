@@ -4,26 +4,147 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 
 	"tg-handler/logging"
 )
 
 // Bot config
 type BotConf struct {
-	Main     MainSettings     `json:"bot_conf"`
-	Optional OptionalSettings `json:"options"`
+	Main        MainSettings        `json:"bot_conf"`
+	Stages      StagesSettings      `json:"stages"`
+	Pipeline    []PipelineStep      `json:"pipeline"` // Replaces response
+	Translation TranslationSettings `json:"translation"`
+	Optional    OptionalSettings    `json:"options"`
 }
 
 // Main settings for LLM
 type MainSettings struct {
-	Role         string `json:"role"`
-	CandidateNum int    `json:"candidate_num"`
+	Role             string             `json:"role"`
+	CandidateNum     int                `json:"candidate_num"`
+	Diversity        DiversitySettings  `json:"diversity"`
+	Repetition       RepetitionSettings `json:"repetition"`
+	Selection        SelectionSettings  `json:"selection"`
+	Refine           bool               `json:"refine"`            // Critique and rewrite
+	LanguageMode     string             `json:"language_mode"`     // Off | reply | translate
+	Format           string             `json:"format"`            // Plain | html | markdown_v2
+	Discussions      bool               `json:"discussions"`       // Comment channel posts
+	Voice            string             `json:"voice"`             // Speech voice, text if empty
+	ReflectionMode   string             `json:"reflection_mode"`   // Combined | separate
+	Models           []ModelSettings    `json:"models"`            // Fallback chain
+	FallbackCooldown Duration           `json:"fallback_cooldown"` // Failed model rest
+}
+
+// Candidate diversity settings
+type DiversitySettings struct {
+	TemperatureStep float32  `json:"temperature_step"` // Added per candidate
+	MaxTemperature  float32  `json:"max_temperature"`  // Ladder ceiling
+	StyleHints      []string `json:"style_hints"`      // Cycled over candidates
+	MaxSimilarity   float64  `json:"max_similarity"`   // 0 keeps duplicates
+	MaxRefills      int      `json:"max_refills"`      // Extra generations
+}
+
+// Repetition guard settings comparing candidates
+// with recent bot lines
+type RepetitionSettings struct {
+	Lines        int            `json:"lines"`         // 0 disables guard
+	NGram        int            `json:"ngram"`         // Word n-gram size
+	MaxOverlap   float64        `json:"max_overlap"`   // N-gram share
+	Action       string         `json:"action"`        // Remove | penalize
+	MaxRegens    int            `json:"max_regens"`    // If all repetitive
+	Embedding    *ModelSettings `json:"embedding"`     // Optional model
+	MaxEmbedding float64        `json:"max_embedding"` // Cosine similarity
+}
+
+// Repetition actions
+const (
+	RepetitionRemove   = "remove"   // Drop repetitive candidates
+	RepetitionPenalize = "penalize" // Lower their selection scores
+)
+
+// Candidate selection settings
+type SelectionSettings struct {
+	Mode          string `json:"mode"`            // Vote | tournament | rubric
+	Votes         int    `json:"votes"`           // Judge calls, 1 if not set
+	Aggregation   string `json:"aggregation"`     // Majority | borda
+	RateBatchSize int    `json:"rate_batch_size"` // Rubric scores averaged
+}
+
+// Selection modes
+const (
+	SelectionVote       = "vote"       // Judge picks from all at once
+	SelectionTournament = "tournament" // Knockout of pairwise votes
+	SelectionRubric     = "rubric"     // Judge scores each by rubric
+)
+
+// Vote aggregations
+const (
+	AggregationMajority = "majority" // Most times chosen as best
+	AggregationBorda    = "borda"    // Best total rank points
+)
+
+// Language modes for detected user language
+const (
+	LanguageOff       = "off"       // Reply in English
+	LanguageReply     = "reply"     // Reply in user language
+	LanguageTranslate = "translate" // Translate English reply to it
+)
+
+// Reply formats
+const (
+	FormatPlain      = "plain"       // As generated
+	FormatHTML       = "html"        // Telegram HTML
+	FormatMarkdownV2 = "markdown_v2" // Telegram MarkdownV2
+)
+
+// Temperature ladder ceiling if not set
+const defaultMaxTemperature = 2
+
+// Reflection modes
+const (
+	ReflectionCombined = "combined" // Carma and tags in one call
+	ReflectionSeparate = "separate" // Carma and tags in two calls
+)
+
+// Stage settings overriding main settings per stage
+type StagesSettings struct {
+	Response  StageSettings `json:"response"`
+	Select    StageSettings `json:"select"`
+	Tags      StageSettings `json:"tags"`
+	Carma     StageSettings `json:"carma"`
+	Reflect   StageSettings `json:"reflect"`   // Combined reflection
+	Refine    StageSettings `json:"refine"`    // Refinement pass
+	Translate StageSettings `json:"translate"` // LLM translation
+	Describe  StageSettings `json:"describe"`  // Image description
+}
+
+// Stage settings
+type StageSettings struct {
+	Models  []ModelSettings  `json:"models"`  // Stage fallback chain
+	Options OptionalSettings `json:"options"` // Merged over bot options
+}
+
+// All stage settings in order
+func (ss *StagesSettings) All() []*StageSettings {
+	return []*StageSettings{
+		&ss.Response, &ss.Select, &ss.Tags, &ss.Carma, &ss.Reflect,
+		&ss.Refine, &ss.Translate, &ss.Describe,
+	}
+}
+
+// Model settings for LLM backend
+type ModelSettings struct {
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint,omitempty"`     // Ollama base URL
+	Timeout     Duration `json:"timeout,omitempty"`      // Request timeout
+	PlainOutput bool     `json:"plain_output,omitempty"` // No JSON schema
+	Vision      bool     `json:"vision,omitempty"`       // Accepts images
 }
 
 // Loads settings or panics
 func MustLoadBotConf(
 	path string,
-	defaults *OptionalSettings,
+	settings *BotSettings,
 	logger *logging.Logger,
 ) *BotConf {
 	var botConf BotConf
@@ -56,44 +177,244 @@ func MustLoadBotConf(
 		)
 	}
 
-	// Validate token limit
-	botConf.Optional = *mergeOptions(&botConf.Optional, defaults)
+	// Merge options: default < bot < stage
+	botConf.Optional = *botConf.Optional.Merge(&settings.DefaultOptions)
+	for _, stage := range botConf.Stages.All() {
+		stage.Options = *stage.Options.Merge(&botConf.Optional)
+	}
 
 	// Validate candidate number or panic
 	mustValidateCandidateNum(&botConf, logger)
 
+	// Validate diversity settings or panic
+	mustValidateDiversity(&botConf, logger)
+
+	// Validate repetition settings setting defaults or panic
+	mustValidateRepetition(&botConf, logger)
+
+	// Validate selection settings or panic
+	mustValidateSelection(&botConf, &settings.PromptTemplates, logger)
+
+	// Validate model chain or panic
+	mustValidateModels(&botConf, logger)
+
+	// Validate refinement or panic
+	if botConf.Main.Refine && settings.PromptTemplates.Refine == "" {
+		logger.Panic(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errEmptyTemplate, "refine"),
+		))
+	}
+
+	// Validate language mode or panic
+	switch botConf.Main.LanguageMode {
+	case "":
+		botConf.Main.LanguageMode = LanguageOff
+	case LanguageOff, LanguageReply, LanguageTranslate:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownLanguageMode))
+	}
+
+	// Validate reply format or panic
+	switch botConf.Main.Format {
+	case "":
+		botConf.Main.Format = FormatHTML
+	case FormatPlain, FormatHTML, FormatMarkdownV2:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownFormat))
+	}
+
+	// Validate translation or panic
+	mustValidateTranslation(&botConf, &settings.PromptTemplates, logger)
+
+	// Validate pipeline or panic
+	mustValidatePipeline(&botConf, logger)
+
+	// Resolve reflection mode or panic
+	mustResolveReflectionMode(
+		&botConf, &settings.PromptTemplates, logger,
+	)
+
 	return &botConf
 }
 
-// Helper to merge options (Bot overrides Default)
-func mergeOptions(bot, def *OptionalSettings) *OptionalSettings {
-	if bot.Temperature == 0 {
-		bot.Temperature = def.Temperature
+// Validates candidate num or panics
+func mustValidateCandidateNum(
+	conf *BotConf, logger *logging.Logger,
+) {
+	const errMsg = "failed to load bot config"
+	if conf.Main.CandidateNum < 0 {
+		logger.Panic(errMsg, logging.Err(errNegCandidateNum))
 	}
-	if bot.RepeatPenalty == 0 {
-		bot.RepeatPenalty = def.RepeatPenalty
+}
+
+// Validates diversity settings setting defaults or panics
+func mustValidateDiversity(
+	conf *BotConf, logger *logging.Logger,
+) {
+	const errMsg = "failed to load bot config"
+
+	diversity := &conf.Main.Diversity
+	if diversity.MaxTemperature < 0 {
+		logger.Panic(errMsg, logging.Err(errNegTemperature))
 	}
-	if bot.TopP == 0 {
-		bot.TopP = def.TopP
+	if diversity.MaxTemperature == 0 {
+		diversity.MaxTemperature = defaultMaxTemperature
 	}
-	if bot.TopK == 0 {
-		bot.TopK = def.TopK
+	if diversity.TemperatureStep < 0 ||
+		diversity.TemperatureStep > diversity.MaxTemperature {
+		logger.Panic(errMsg, logging.Err(errStepOOB))
 	}
-	if bot.NumPredict == 0 {
-		bot.NumPredict = def.NumPredict
+	if diversity.MaxSimilarity < 0 || diversity.MaxSimilarity > 1 {
+		logger.Panic(errMsg, logging.Err(errSimilarityOOB))
 	}
-	if bot.Seed == 0 {
-		bot.Seed = def.Seed
+	if diversity.MaxRefills < 0 {
+		logger.Panic(errMsg, logging.Err(errNegRefills))
 	}
-	return bot
 }
 
-// Validates candidate num or panics
-func mustValidateCandidateNum(
+// Validates repetition settings setting defaults or panics
+func mustValidateRepetition(
 	conf *BotConf, logger *logging.Logger,
+) {
+	const (
+		errMsg = "failed to load bot config"
+
+		defaultNGram        = 3
+		defaultMaxOverlap   = 0.5
+		defaultMaxEmbedding = 0.9
+	)
+
+	repetition := &conf.Main.Repetition
+	if repetition.Lines < 0 || repetition.NGram < 0 ||
+		repetition.MaxRegens < 0 {
+		logger.Panic(errMsg, logging.Err(errNegRepetition))
+	}
+	if repetition.MaxOverlap < 0 || repetition.MaxOverlap > 1 ||
+		repetition.MaxEmbedding < 0 || repetition.MaxEmbedding > 1 {
+		logger.Panic(errMsg, logging.Err(errSimilarityOOB))
+	}
+	if embedding := repetition.Embedding; embedding != nil {
+		if embedding.Name == "" {
+			logger.Panic(errMsg, logging.Err(errEmptyModelName))
+		}
+		if embedding.Timeout < 0 {
+			logger.Panic(errMsg, logging.Err(errNegTimeout))
+		}
+	}
+
+	// Set defaults
+	if repetition.NGram == 0 {
+		repetition.NGram = defaultNGram
+	}
+	if repetition.MaxOverlap == 0 {
+		repetition.MaxOverlap = defaultMaxOverlap
+	}
+	if repetition.MaxEmbedding == 0 {
+		repetition.MaxEmbedding = defaultMaxEmbedding
+	}
+
+	switch repetition.Action {
+	case "":
+		repetition.Action = RepetitionRemove
+	case RepetitionRemove, RepetitionPenalize:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownRepetitionAction))
+	}
+}
+
+// Validates selection settings setting defaults or panics
+func mustValidateSelection(
+	conf *BotConf, templates *PromptTemplates, logger *logging.Logger,
 ) {
 	const errMsg = "failed to load bot config"
-	if conf.Main.CandidateNum < 0 {
-		logger.Panic(errMsg, logging.Err(errNegCandidateNum))
+
+	selection := &conf.Main.Selection
+	switch selection.Mode {
+	case "":
+		selection.Mode = SelectionVote
+	case SelectionRubric:
+		if templates.Rate == "" {
+			logger.Panic(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errEmptyTemplate, "rate"),
+			))
+		}
+	case SelectionVote, SelectionTournament:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownSelectionMode))
+	}
+
+	if selection.Votes < 0 {
+		logger.Panic(errMsg, logging.Err(errNegVotes))
+	}
+	if selection.RateBatchSize < 0 {
+		logger.Panic(errMsg, logging.Err(errNegRateBatch))
+	}
+	if selection.Votes == 0 {
+		selection.Votes = 1
+	}
+	if selection.RateBatchSize == 0 {
+		selection.RateBatchSize = 1
+	}
+
+	switch selection.Aggregation {
+	case "":
+		selection.Aggregation = AggregationMajority
+	case AggregationBorda:
+		if templates.Rank == "" {
+			logger.Panic(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errEmptyTemplate, "rank"),
+			))
+		}
+	case AggregationMajority:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownAggregation))
+	}
+}
+
+// Validates model chain or panics
+func mustValidateModels(
+	conf *BotConf, logger *logging.Logger,
+) {
+	const errMsg = "failed to load bot config"
+
+	// Collect bot and stage models
+	models := slices.Clone(conf.Main.Models)
+	for _, stage := range conf.Stages.All() {
+		models = append(models, stage.Models...)
+	}
+
+	for _, model := range models {
+		if model.Name == "" {
+			logger.Panic(errMsg, logging.Err(errEmptyModelName))
+		}
+		if model.Timeout < 0 {
+			logger.Panic(errMsg, logging.Err(errNegTimeout))
+		}
+	}
+	if conf.Main.FallbackCooldown < 0 {
+		logger.Panic(errMsg, logging.Err(errNegCooldown))
+	}
+}
+
+// Resolves reflection mode or panics: separate by default,
+// combined only if chosen and reflect template is set
+func mustResolveReflectionMode(
+	conf *BotConf, templates *PromptTemplates, logger *logging.Logger,
+) {
+	const errMsg = "failed to load bot config"
+
+	mainSettings := &conf.Main
+	switch mainSettings.ReflectionMode {
+	case "":
+		mainSettings.ReflectionMode = ReflectionSeparate
+	case ReflectionCombined:
+		if templates.Reflect == "" {
+			logger.Panic(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errEmptyTemplate, "reflect"),
+			))
+		}
+	case ReflectionSeparate:
+	default:
+		logger.Panic(errMsg, logging.Err(errUnknownReflectionMode))
 	}
 }
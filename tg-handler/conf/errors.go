@@ -18,6 +18,39 @@ var (
 	errPlaceholderOverflow  = errors.New("counted more than needed")
 	errPlaceholderUnderflow = errors.New("counted less than needed")
 
+	// Init config errors
+	errNegWorkers = errors.New("negative worker number")
+	errInvalidURL = errors.New("invalid URL")
+
 	// Bot config errors
 	errNegCandidateNum = errors.New("negative candidate number")
+	errEmptyModelName  = errors.New("empty model name")
+	errNegTimeout      = errors.New("negative model timeout")
+	errBadKeepAlive    = errors.New("keep alive is not string or number")
+	errNegCooldown     = errors.New("negative fallback cooldown")
+
+	errNegTemperature = errors.New("negative max temperature")
+	errStepOOB        = errors.New("temperature step is out of bounds")
+
+	errSimilarityOOB = errors.New("max similarity is out of bounds 0-1")
+	errNegRefills    = errors.New("negative refill number")
+	errNegRepetition = errors.New("negative repetition setting")
+
+	errUnknownRepetitionAction = errors.New("unknown repetition action")
+	errUnknownTranslator       = errors.New("unknown translator")
+	errUnknownLanguageMode     = errors.New("unknown language mode")
+	errUnknownFormat           = errors.New("unknown reply format")
+
+	errUnknownReflectionMode = errors.New("unknown reflection mode")
+	errNegVotes              = errors.New("negative vote number")
+	errNegRateBatch          = errors.New("negative rate batch size")
+	errUnknownSelectionMode  = errors.New("unknown selection mode")
+	errUnknownAggregation    = errors.New("unknown vote aggregation")
+
+	// Pipeline errors
+	errEmptyStepOutput     = errors.New("empty pipeline step output")
+	errDuplicateStepOutput = errors.New("duplicate pipeline step output")
+	errTemplateParseFailed = errors.New("parse step template failed")
+	errUndefinedStepVar    = errors.New("undefined step variable")
+	errNoResponseStep      = errors.New("last pipeline step is not response")
 )
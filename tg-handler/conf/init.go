@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,19 +24,45 @@ const (
 
 	carmaSNum = 6
 	carmaDNum = 0
+
+	reflectSNum = 8
+	reflectDNum = 1
+
+	rateSNum = 3
+	rateDNum = 0
+
+	rankSNum = 3
+	rankDNum = 1
+
+	refineSNum = 3
+	refineDNum = 0
+
+	translateSNum = 3
+	translateDNum = 0
+
+	describeSNum = 1
+	describeDNum = 0
 )
 
 // Initialization config
 type InitConf struct {
-	Paths           Paths           `json:"paths"`
-	CleanerSettings CleanerSettings `json:"cleaner_settings"`
-	BotSettings     BotSettings     `json:"bot_settings"`
+	Paths             Paths             `json:"paths"`
+	CleanerSettings   CleanerSettings   `json:"cleaner_settings"`
+	SchedulerSettings SchedulerSettings `json:"scheduler_settings"`
+	LanguageSettings  LanguageSettings  `json:"language_settings"`
+	SendingSettings   SendingSettings   `json:"sending_settings"`
+	TelegramSettings  TelegramSettings  `json:"telegram_settings"`
+	MediaSettings     MediaSettings     `json:"media_settings"`
+	SpeechSettings    SpeechSettings    `json:"speech_settings"`
+	BotSettings       BotSettings       `json:"bot_settings"`
 }
 
 // Paths
 type Paths struct {
-	History     string `json:"history"`
-	BotsConfDir string `json:"bots_conf_dir"`
+	History        string `json:"history"`
+	BotsConfDir    string `json:"bots_conf_dir"`
+	ReflectionsDir string `json:"reflections_dir"` // Queues per bot
+	OutboxDir      string `json:"outbox_dir"`      // Outboxes per bot
 }
 
 // Cleaner settings
@@ -44,26 +71,100 @@ type CleanerSettings struct {
 	CleanupInterval Duration `json:"cleanup_interval"`
 }
 
+// LLM request scheduler settings
+type SchedulerSettings struct {
+	MaxInFlight     int      `json:"max_in_flight"`    // 1 if not set
+	MetricsInterval Duration `json:"metrics_interval"` // 1m if not set
+}
+
+// Language detection settings
+type LanguageSettings struct {
+	Languages   []string `json:"languages"`    // ISO 639-1, all if empty
+	MinDistance float64  `json:"min_distance"` // Relative, 0-0.99
+	LowAccuracy bool     `json:"low_accuracy"` // Faster, less memory
+}
+
+// Telegram sending settings, per bot
+type SendingSettings struct {
+	ChatInterval   Duration `json:"chat_interval"`   // 1s if not set
+	GlobalInterval Duration `json:"global_interval"` // 35ms if not set
+	MaxRetries     int      `json:"max_retries"`     // 3 if not set
+	Backoff        Duration `json:"backoff"`         // 1s if not set, doubled
+	MaxWait        Duration `json:"max_wait"`        // 1m if not set
+	OutboxInterval Duration `json:"outbox_interval"` // 1m if not set
+	OutboxAttempts int      `json:"outbox_attempts"` // 5 if not set
+}
+
+// Incoming media settings
+type MediaSettings struct {
+	MaxImageSize int `json:"max_image_size"` // Bytes, 5MB if not set
+	MaxVoiceSize int `json:"max_voice_size"` // Bytes, 20MB if not set
+}
+
 // Bot settings
 type BotSettings struct {
-	PromptTemplates PromptTemplates  `json:"prompt_templates"`
-	AllowedChats    AllowedChats     `json:"allowed_chats"`
-	MemoryLimits    MemoryLimits     `json:"memory_limits"`
-	DefaultOptions  OptionalSettings `json:"default_options"`
+	PromptTemplates    PromptTemplates    `json:"prompt_templates"`
+	AllowedChats       AllowedChats       `json:"allowed_chats"`
+	MemoryLimits       MemoryLimits       `json:"memory_limits"`
+	ReflectionSettings ReflectionSettings `json:"reflection_settings"`
+	DefaultOptions     OptionalSettings   `json:"default_options"`
+	Descriptions       Descriptions       `json:"descriptions"`
+}
+
+// Non-text message descriptions in history, per kind
+type Descriptions struct {
+	Stickers   bool `json:"stickers"`
+	Polls      bool `json:"polls"`
+	Locations  bool `json:"locations"` // And venues
+	Contacts   bool `json:"contacts"`
+	Dice       bool `json:"dice"`
+	Animations bool `json:"animations"` // GIFs
+	Documents  bool `json:"documents"`
+	Triggering bool `json:"triggering"` // Replied like text messages
+}
+
+// Reflection queue settings
+type ReflectionSettings struct {
+	Workers int `json:"workers"` // Per bot, 1 if not set
 }
 
 // Allowed chats
 type AllowedChats struct {
-	Usernames []string `json:"usernames"`
-	IDs       []int64  `json:"ids"`
+	Usernames []string              `json:"usernames"`
+	IDs       []int64               `json:"ids"`
+	Topics    map[int64]TopicFilter `json:"topics"` // By chat ID
+}
+
+// Allowed forum topics of chat by thread ID, 0 for general topic
+type TopicFilter struct {
+	Allow []int `json:"allow"` // Only these if set
+	Deny  []int `json:"deny"`
+}
+
+// Reports if forum topic of allowed chat is allowed
+func (ac *AllowedChats) IsTopicAllowed(chatID int64, threadID int) bool {
+	filter, ok := ac.Topics[chatID]
+	if !ok {
+		return true
+	}
+	if len(filter.Allow) > 0 && !slices.Contains(filter.Allow, threadID) {
+		return false
+	}
+	return !slices.Contains(filter.Deny, threadID)
 }
 
 // Prompt templates
 type PromptTemplates struct {
-	Response string `json:"response"`
-	Select   string `json:"select"`
-	Tags     string `json:"tags"`
-	Carma    string `json:"carma"`
+	Response  string `json:"response"`
+	Select    string `json:"select"`
+	Tags      string `json:"tags"`
+	Carma     string `json:"carma"`
+	Reflect   string `json:"reflect"`   // Optional: combined reflection
+	Rate      string `json:"rate"`      // Optional: rubric selection
+	Rank      string `json:"rank"`      // Optional: Borda ranking
+	Refine    string `json:"refine"`    // Optional: refinement pass
+	Translate string `json:"translate"` // Optional: LLM translation
+	Describe  string `json:"describe"`  // Optional: image description
 }
 
 // Memory limits
@@ -131,9 +232,35 @@ func MustLoadInitConf(
 		logger,
 	)
 
+	// Validate reflection settings or panic
+	mustValidateReflectionSettings(
+		&initConf.BotSettings.ReflectionSettings,
+		logger,
+	)
+
+	// Validate Telegram settings or panic
+	mustValidateTelegramSettings(&initConf.TelegramSettings, logger)
+
+	// Validate speech settings or panic
+	mustValidateSpeechSettings(&initConf.SpeechSettings, logger)
+
 	return &initConf
 }
 
+// Validates reflection settings setting defaults or panics
+func mustValidateReflectionSettings(
+	settings *ReflectionSettings, logger *logging.Logger,
+) {
+	const errMsg = "failed to validate reflection settings"
+
+	if settings.Workers < 0 {
+		logger.Panic(errMsg, logging.Err(errNegWorkers))
+	}
+	if settings.Workers == 0 {
+		settings.Workers = 1
+	}
+}
+
 // Validates prompt templates
 func mustValidateTemplates(
 	templates *PromptTemplates, logger *logging.Logger,
@@ -142,6 +269,24 @@ func mustValidateTemplates(
 	mustValidateSelectTemplate(templates.Select, logger)
 	mustValidateTagsTemplate(templates.Tags, logger)
 	mustValidateCarmaTemplate(templates.Carma, logger)
+	if templates.Reflect != "" {
+		mustValidateReflectTemplate(templates.Reflect, logger)
+	}
+	if templates.Rate != "" {
+		mustValidateRateTemplate(templates.Rate, logger)
+	}
+	if templates.Rank != "" {
+		mustValidateRankTemplate(templates.Rank, logger)
+	}
+	if templates.Refine != "" {
+		mustValidateRefineTemplate(templates.Refine, logger)
+	}
+	if templates.Translate != "" {
+		mustValidateTranslateTemplate(templates.Translate, logger)
+	}
+	if templates.Describe != "" {
+		mustValidateDescribeTemplate(templates.Describe, logger)
+	}
 }
 
 // Validates response template or panics
@@ -185,6 +330,72 @@ func mustValidateCarmaTemplate(
 	mustValidateNumOf(template, "%d", carmaDNum, logger)
 }
 
+// Validates reflect template or panics
+func mustValidateReflectTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("reflect"))
+
+	mustValidateNumOf(template, "%s", reflectSNum, logger)
+	mustValidateNumOf(template, "%d", reflectDNum, logger)
+}
+
+// Validates rate template or panics
+func mustValidateRateTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("rate"))
+
+	mustValidateNumOf(template, "%s", rateSNum, logger)
+	mustValidateNumOf(template, "%d", rateDNum, logger)
+}
+
+// Validates rank template or panics
+func mustValidateRankTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("rank"))
+
+	mustValidateNumOf(template, "%s", rankSNum, logger)
+	mustValidateNumOf(template, "%d", rankDNum, logger)
+}
+
+// Validates refine template or panics
+func mustValidateRefineTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("refine"))
+
+	mustValidateNumOf(template, "%s", refineSNum, logger)
+	mustValidateNumOf(template, "%d", refineDNum, logger)
+}
+
+// Validates translate template or panics
+func mustValidateTranslateTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("translate"))
+
+	mustValidateNumOf(template, "%s", translateSNum, logger)
+	mustValidateNumOf(template, "%d", translateDNum, logger)
+}
+
+// Validates describe template or panics
+func mustValidateDescribeTemplate(
+	template string,
+	logger *logging.Logger,
+) {
+	logger = logger.With(logging.TemplateType("describe"))
+
+	mustValidateNumOf(template, "%s", describeSNum, logger)
+	mustValidateNumOf(template, "%d", describeDNum, logger)
+}
+
 // Validates number of template placeholders or panic
 func mustValidateNumOf(
 	template string,
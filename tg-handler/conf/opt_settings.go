@@ -1,11 +1,81 @@
 package conf
 
-// Optional settings for LLM
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Optional settings for LLM, nil means unset so zero is explicit
 type OptionalSettings struct {
-	Temperature   float32 `json:"temperature,omitempty"`
-	RepeatPenalty float32 `json:"repeat_penalty,omitempty"`
-	TopP          float32 `json:"top_p,omitempty"`
-	TopK          int     `json:"top_k,omitempty"`
-	NumPredict    int     `json:"num_predict,omitempty"`
-	Seed          int     `json:"seed,omitempty"`
+	Temperature      *float32 `json:"temperature,omitempty"`
+	RepeatPenalty    *float32 `json:"repeat_penalty,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	MinP             *float32 `json:"min_p,omitempty"`
+	NumPredict       *int     `json:"num_predict,omitempty"`
+	NumCtx           *int     `json:"num_ctx,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	Mirostat         *int     `json:"mirostat,omitempty"`
+	MirostatEta      *float32 `json:"mirostat_eta,omitempty"`
+	MirostatTau      *float32 `json:"mirostat_tau,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+
+	// Set empty list is sent to clear model stop sequences
+	Stop *[]string `json:"stop,omitempty"`
+
+	// Request level, moved out of options on sending
+	KeepAlive *KeepAlive `json:"keep_alive,omitempty"`
+}
+
+// Ollama keep alive: duration ("5m") or seconds (-1, 0) as is
+type KeepAlive json.RawMessage
+
+func (k *KeepAlive) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case string, float64:
+	default:
+		return errBadKeepAlive
+	}
+	*k = slices.Clone(b)
+	return nil
+}
+
+func (k KeepAlive) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(k).MarshalJSON()
+}
+
+// Merges options (set fields override base ones)
+func (o *OptionalSettings) Merge(base *OptionalSettings) *OptionalSettings {
+	merged := &OptionalSettings{
+		Temperature:      pick(o.Temperature, base.Temperature),
+		RepeatPenalty:    pick(o.RepeatPenalty, base.RepeatPenalty),
+		TopP:             pick(o.TopP, base.TopP),
+		TopK:             pick(o.TopK, base.TopK),
+		MinP:             pick(o.MinP, base.MinP),
+		NumPredict:       pick(o.NumPredict, base.NumPredict),
+		NumCtx:           pick(o.NumCtx, base.NumCtx),
+		Seed:             pick(o.Seed, base.Seed),
+		Stop:             pick(o.Stop, base.Stop),
+		Mirostat:         pick(o.Mirostat, base.Mirostat),
+		MirostatEta:      pick(o.MirostatEta, base.MirostatEta),
+		MirostatTau:      pick(o.MirostatTau, base.MirostatTau),
+		PresencePenalty:  pick(o.PresencePenalty, base.PresencePenalty),
+		FrequencyPenalty: pick(o.FrequencyPenalty, base.FrequencyPenalty),
+		KeepAlive:        pick(o.KeepAlive, base.KeepAlive),
+	}
+
+	return merged
+}
+
+// Picks set value over base one
+func pick[T any](v, base *T) *T {
+	if v != nil {
+		return v
+	}
+	return base
 }
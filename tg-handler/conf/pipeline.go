@@ -0,0 +1,93 @@
+package conf
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/template"
+
+	"tg-handler/logging"
+)
+
+// Pipeline variables set before the first step
+const (
+	VarBot      = "bot"      // Bot name
+	VarChat     = "chat"     // Chat title
+	VarMemory   = "memory"   // Chat memory
+	VarLanguage = "language" // Reply language name
+)
+
+// Output of the last step, generated as candidates
+const OutputResponse = "response"
+
+// Step of chain-of-thought pipeline producing response.
+// Template references variables as {{.name}}: the built-in ones
+// and outputs of previous steps.
+type PipelineStep struct {
+	Output   string           `json:"output"`   // Variable to be set
+	Template string           `json:"template"` // Go text template
+	Options  OptionalSettings `json:"options"`  // Merged over response
+
+	tmpl *template.Template
+}
+
+// Gets parsed template
+func (s *PipelineStep) Tmpl() *template.Template {
+	return s.tmpl
+}
+
+// Validates pipeline parsing templates or panics:
+// outputs are unique, defined before use, the last one is response
+func mustValidatePipeline(conf *BotConf, logger *logging.Logger) {
+	const errMsg = "failed to load bot config"
+
+	pipeline := conf.Pipeline
+	if len(pipeline) == 0 {
+		return
+	}
+
+	defined := []string{VarBot, VarChat, VarMemory, VarLanguage}
+	for i := range pipeline {
+		step := &pipeline[i]
+		stepLog := logger.With(logging.Step(step.Output))
+
+		// Validate output
+		if step.Output == "" {
+			stepLog.Panic(errMsg, logging.Err(errEmptyStepOutput))
+		}
+		if slices.Contains(defined, step.Output) {
+			stepLog.Panic(errMsg, logging.Err(errDuplicateStepOutput))
+		}
+
+		// Parse template failing on undefined variables
+		tmpl, err := template.New(step.Output).
+			Option("missingkey=error").
+			Parse(step.Template)
+		if err != nil {
+			stepLog.Panic(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errTemplateParseFailed, err),
+			))
+		}
+
+		// Check template executes on defined variables
+		vars := make(map[string]string, len(defined))
+		for _, name := range defined {
+			vars[name] = ""
+		}
+		if err := tmpl.Execute(io.Discard, vars); err != nil {
+			stepLog.Panic(errMsg, logging.Err(
+				fmt.Errorf("%w: %v", errUndefinedStepVar, err),
+			))
+		}
+
+		// Merge options: bot < response stage < step
+		step.Options = *step.Options.Merge(&conf.Stages.Response.Options)
+
+		step.tmpl = tmpl
+		defined = append(defined, step.Output)
+	}
+
+	if pipeline[len(pipeline)-1].Output != OutputResponse {
+		logger.Panic(errMsg, logging.Err(errNoResponseStep))
+	}
+}
@@ -0,0 +1,39 @@
+package conf
+
+import (
+	"tg-handler/logging"
+)
+
+// Speech settings, each direction is off if its endpoint is not set
+type SpeechSettings struct {
+	Transcription TranscriptionSettings `json:"transcription"`
+	Synthesis     SynthesisSettings     `json:"synthesis"`
+}
+
+// Speech-to-text settings for whisper.cpp compatible server
+type TranscriptionSettings struct {
+	Endpoint string   `json:"endpoint"` // Base URL
+	Language string   `json:"language"` // ISO 639-1, auto if empty
+	Timeout  Duration `json:"timeout"`  // 2m if not set
+}
+
+// Text-to-speech settings for OpenAI compatible speech server,
+// voices are set per bot
+type SynthesisSettings struct {
+	Endpoint string   `json:"endpoint"` // Base URL
+	Model    string   `json:"model"`    // Server default if empty
+	Timeout  Duration `json:"timeout"`  // 2m if not set
+}
+
+// Validates speech settings or panics
+func mustValidateSpeechSettings(
+	settings *SpeechSettings, logger *logging.Logger,
+) {
+	schemes := []string{"http", "https"}
+	if endpoint := settings.Transcription.Endpoint; endpoint != "" {
+		mustValidateURL(endpoint, schemes, logger)
+	}
+	if endpoint := settings.Synthesis.Endpoint; endpoint != "" {
+		mustValidateURL(endpoint, schemes, logger)
+	}
+}
@@ -0,0 +1,73 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"tg-handler/logging"
+)
+
+// Official Bot API base URL
+const DefaultAPIURL = "https://api.telegram.org"
+
+// Telegram Bot API settings with per bot overrides
+type TelegramSettings struct {
+	EndpointSettings
+	Bots map[int64]EndpointSettings `json:"bots"` // By bot ID (key prefix)
+}
+
+// Bot API endpoint settings, unset fields are inherited
+type EndpointSettings struct {
+	APIURL string `json:"api_url"` // Base URL, official if empty
+	Proxy  string `json:"proxy"`   // Proxy URL, environment if empty
+}
+
+// Gets endpoint settings for bot
+func (ts *TelegramSettings) For(botID int64) EndpointSettings {
+	bot, ok := ts.Bots[botID]
+	if !ok {
+		return ts.EndpointSettings
+	}
+
+	return EndpointSettings{
+		APIURL: or(bot.APIURL, ts.APIURL),
+		Proxy:  or(bot.Proxy, ts.Proxy),
+	}
+}
+
+// Validates Telegram settings setting defaults or panics
+func mustValidateTelegramSettings(
+	settings *TelegramSettings, logger *logging.Logger,
+) {
+	settings.APIURL = or(settings.APIURL, DefaultAPIURL)
+
+	// Validate bot and default endpoints
+	endpoints := make([]EndpointSettings, 0, len(settings.Bots)+1)
+	endpoints = append(endpoints, settings.EndpointSettings)
+	for botID := range settings.Bots {
+		endpoints = append(endpoints, settings.For(botID))
+	}
+	for _, endpoint := range endpoints {
+		mustValidateURL(endpoint.APIURL, []string{"http", "https"}, logger)
+		if endpoint.Proxy != "" {
+			mustValidateURL(
+				endpoint.Proxy, []string{"http", "https", "socks5"}, logger,
+			)
+		}
+	}
+}
+
+// Validates absolute URL with one of schemes or panics
+func mustValidateURL(
+	rawURL string, schemes []string, logger *logging.Logger,
+) {
+	const errMsg = "failed to validate URL"
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || !slices.Contains(schemes, u.Scheme) {
+		logger.Panic(errMsg, logging.Err(
+			fmt.Errorf("%w: %q", errInvalidURL, rawURL),
+		))
+	}
+}
@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"fmt"
+
+	"tg-handler/logging"
+)
+
+// Translators
+const (
+	TranslatorNone   = "none"   // Send as generated
+	TranslatorLLM    = "llm"    // Translate with model backend
+	TranslatorGoogle = "google" // Translate with Google Translate
+)
+
+// Translation defaults kept from hard-coded ones
+const (
+	defaultTranslator = TranslatorGoogle
+	defaultFrom       = "en"
+	defaultTo         = "eo"
+)
+
+// Translation settings of bot with per chat overrides
+type TranslationSettings struct {
+	ChatTranslation
+	Chats map[int64]ChatTranslation `json:"chats"` // By chat ID
+}
+
+// Translation settings for chat, unset fields are inherited
+type ChatTranslation struct {
+	Translator string `json:"translator"` // None | llm | google
+	From       string `json:"from"`       // Source language code
+	To         string `json:"to"`         // Target language code
+}
+
+// Gets translation settings for chat
+func (ts *TranslationSettings) For(chatID int64) ChatTranslation {
+	chat, ok := ts.Chats[chatID]
+	if !ok {
+		return ts.ChatTranslation
+	}
+
+	return ChatTranslation{
+		Translator: or(chat.Translator, ts.Translator),
+		From:       or(chat.From, ts.From),
+		To:         or(chat.To, ts.To),
+	}
+}
+
+// Picks set string over base one
+func or(v, base string) string {
+	if v != "" {
+		return v
+	}
+	return base
+}
+
+// Validates translation settings setting defaults or panics
+func mustValidateTranslation(
+	conf *BotConf, templates *PromptTemplates, logger *logging.Logger,
+) {
+	const errMsg = "failed to load bot config"
+
+	translation := &conf.Translation
+	translation.Translator = or(translation.Translator, defaultTranslator)
+	translation.From = or(translation.From, defaultFrom)
+	translation.To = or(translation.To, defaultTo)
+
+	// Validate bot and chat translators
+	chats := make([]ChatTranslation, 0, len(translation.Chats)+1)
+	chats = append(chats, translation.ChatTranslation)
+	for chatID := range translation.Chats {
+		chats = append(chats, translation.For(chatID))
+	}
+	for _, chat := range chats {
+		switch chat.Translator {
+		case TranslatorLLM:
+			if templates.Translate == "" {
+				logger.Panic(errMsg, logging.Err(
+					fmt.Errorf("%w: %v", errEmptyTemplate, "translate"),
+				))
+			}
+		case TranslatorNone, TranslatorGoogle:
+		default:
+			logger.Panic(errMsg, logging.Err(errUnknownTranslator))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package critique
+
+import (
+	"errors"
+	"strings"
+
+	"tg-handler/schema"
+)
+
+// Verdicts
+const (
+	VerdictKeep    = "keep"
+	VerdictRewrite = "rewrite"
+)
+
+// Critique errors
+var (
+	errUnknownVerdict = errors.New("unknown verdict")
+	errEmptyRewrite   = errors.New("empty rewrite")
+)
+
+// Structured critique output
+type Output struct {
+	Critique string `json:"critique"`
+	Verdict  string `json:"verdict" enum:"keep,rewrite"`
+	Rewrite  string `json:"rewrite"` // Empty to keep
+}
+
+// Schema for structured critique output
+var Schema = schema.MustOf(Output{})
+
+// Critique of reply with rewritten text if needed
+type Critique struct {
+	Critique string
+	Rewrite  bool
+	Text     string
+}
+
+// Parses structured output, falls back to free text
+func Parse(s string) (*Critique, error) {
+	var output Output
+	if err := schema.Decode(s, &output); err != nil {
+		output = parseFree(s)
+	}
+
+	critique := &Critique{
+		Critique: strings.TrimSpace(output.Critique),
+		Text:     strings.TrimSpace(output.Rewrite),
+	}
+	switch output.Verdict {
+	case VerdictKeep:
+	case VerdictRewrite:
+		if critique.Text == "" {
+			return nil, errEmptyRewrite
+		}
+		critique.Rewrite = true
+	default:
+		return nil, errUnknownVerdict
+	}
+
+	return critique, nil
+}
+
+// Parses free text: verdict line followed by rewrite if any,
+// e.g. "REWRITE:\nNew reply" or "**KEEP**", marks are trimmed
+func parseFree(s string) Output {
+	head, rest, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	verdict, inline, _ := strings.Cut(head, ":")
+	return Output{
+		Verdict: strings.ToLower(strings.Trim(verdict, " .*_`")),
+		Rewrite: strings.TrimSpace(inline + "\n" + rest),
+	}
+}
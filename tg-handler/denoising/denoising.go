@@ -15,6 +15,11 @@ func DenoiseResponse(
 	return s
 }
 
+// Removes noise from structured model response
+func DenoiseStructured(s string) string {
+	return trimThinking(s)
+}
+
 // Removes thinking part
 func trimThinking(s string) string {
 	const (
@@ -3,7 +3,6 @@ module tg-handler
 go 1.25.5
 
 require (
-	github.com/bregydoc/gtranslate v0.0.0-20200913051839-1bd07f6c1fc5
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/pemistahl/lingua-go v1.4.0
 	golang.org/x/sync v0.19.0
@@ -12,8 +11,6 @@ require (
 )
 
 require (
-	github.com/robertkrimen/otto v0.5.1 // indirect
 	github.com/shopspring/decimal v1.3.1 // indirect
 	golang.org/x/exp v0.0.0-20221106115401-f9659909a136 // indirect
-	gopkg.in/sourcemap.v1 v1.0.5 // indirect
 )
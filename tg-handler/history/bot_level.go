@@ -2,6 +2,7 @@ package history
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
@@ -13,6 +14,7 @@ import (
 const (
 	botHistoryCap  = 256
 	botContactsCap = 256
+	handledCap     = 64 // Message IDs remembered per chat
 )
 
 // BOT DATA
@@ -22,12 +24,14 @@ const (
 type BotData struct {
 	History  *SafeBotHistory  // Read-only
 	Contacts *SafeBotContacts // Read-only
+	Updates  *SafeBotUpdates  // Read-only
 }
 
 func NewBotData() *BotData {
 	return &BotData{
 		History:  NewSafeBotHistory(),
 		Contacts: NewSafeBotContacts(),
+		Updates:  NewSafeBotUpdates(),
 	}
 }
 
@@ -44,7 +48,7 @@ func NewSafeBotHistory() *SafeBotHistory {
 	}
 }
 
-type BotHistory map[int64]*ChatHistory
+type BotHistory map[ChatKey]*ChatHistory
 
 func NewBotHistory() BotHistory {
 	h := make(BotHistory, botHistoryCap)
@@ -100,15 +104,39 @@ func (bcs BotContacts) String() string {
 	return sb.String()
 }
 
+// BOT UPDATES BRANCH
+
+// Processed updates, persisted for restarts to be idempotent
+type SafeBotUpdates struct {
+	mu           sync.Mutex
+	LastUpdateID int
+	Handled      map[int64][]int  // Recent message IDs per chat
+	Recorded     map[int64][]int  // Message IDs added to history per chat
+	pending      map[int]struct{} // Updates in progress, not persisted
+}
+
+func NewSafeBotUpdates() *SafeBotUpdates {
+	return &SafeBotUpdates{
+		Handled:  make(map[int64][]int),
+		Recorded: make(map[int64][]int),
+		pending:  make(map[int]struct{}),
+	}
+}
+
 // BOT CONTACT
 
 type BotContact struct {
-	Carma carma.Carma
-	Tags  tags.Tags
+	Carma    carma.Carma
+	Tags     tags.Tags
+	Language string // ISO 639-1 code, empty if unknown
 }
 
 func (bc BotContact) String() string {
-	return fmt.Sprintf("carma: %d\ntags: %s\n", bc.Carma, bc.Tags)
+	s := fmt.Sprintf("carma: %d\ntags: %s\n", bc.Carma, bc.Tags)
+	if bc.Language != "" {
+		s += fmt.Sprintf("language: %s\n", bc.Language)
+	}
+	return s
 }
 
 // METHODS
@@ -117,30 +145,30 @@ func (bc BotContact) String() string {
 
 // Gets safe chat history and status
 func (sbh *SafeBotHistory) Get(
-	cid int64,
+	key ChatKey,
 	scq *SafeChatQueue, // Preinit for public, nil for private chats
 ) (*ChatHistory, bool) {
 	// Happy path: return existing chat history
-	if chatHistory, ok := sbh.get(cid); ok {
+	if chatHistory, ok := sbh.get(key); ok {
 		return chatHistory, true
 	}
 
 	// Unhappy path: return new chat history
-	return sbh.init(cid, scq), false
+	return sbh.init(key, scq), false
 
 }
 
-func (sbh *SafeBotHistory) get(cid int64) (*ChatHistory, bool) {
+func (sbh *SafeBotHistory) get(key ChatKey) (*ChatHistory, bool) {
 	// Ensure secure access
 	sbh.mu.RLock()
 	defer sbh.mu.RUnlock()
 
-	chatHistory, ok := sbh.History[cid]
+	chatHistory, ok := sbh.History[key]
 	return chatHistory, ok
 }
 
 func (sbh *SafeBotHistory) init(
-	cid int64,
+	key ChatKey,
 	scq *SafeChatQueue, // Preinit for public, nil for private chats
 ) *ChatHistory {
 	// Ensure secure access
@@ -148,13 +176,13 @@ func (sbh *SafeBotHistory) init(
 	defer sbh.mu.Unlock()
 
 	// Double check if init after lock release
-	if chatHistory, ok := sbh.History[cid]; ok {
+	if chatHistory, ok := sbh.History[key]; ok {
 		return chatHistory
 	}
 
 	// Return new chat history
 	chatHistory := NewChatHistory(scq)
-	sbh.History[cid] = chatHistory
+	sbh.History[key] = chatHistory
 	return chatHistory
 }
 
@@ -187,3 +215,117 @@ func (sbcs *SafeBotContacts) Set(
 	// Set bot contact
 	sbcs.Contacts[userName] = botContact
 }
+
+// Applies reflection to bot contact carma and tags only,
+// keeping concurrently set language
+func (sbcs *SafeBotContacts) SetReflection(
+	userName string,
+	carmaUpdate carma.Update,
+	tags tags.Tags,
+) {
+	// Ensure secure access
+	sbcs.mu.Lock()
+	defer sbcs.mu.Unlock()
+
+	// Update carma and tags of bot contact
+	botContact := sbcs.Contacts[userName]
+	botContact.Carma.Apply(carmaUpdate)
+	botContact.Tags = tags
+	sbcs.Contacts[userName] = botContact
+}
+
+// Sets bot contact language only, keeping concurrent reflection
+func (sbcs *SafeBotContacts) SetLanguage(
+	userName string,
+	language string,
+) {
+	// Ensure secure access
+	sbcs.mu.Lock()
+	defer sbcs.mu.Unlock()
+
+	// Set language of bot contact
+	botContact := sbcs.Contacts[userName]
+	botContact.Language = language
+	sbcs.Contacts[userName] = botContact
+}
+
+// BOT UPDATES BRANCH
+
+// Gets offset to resume updates from
+func (sbus *SafeBotUpdates) Offset() int {
+	// Ensure secure access
+	sbus.mu.Lock()
+	defer sbus.mu.Unlock()
+
+	if sbus.LastUpdateID == 0 {
+		return 0
+	}
+	return sbus.LastUpdateID + 1
+}
+
+// Marks update in progress until finished
+func (sbus *SafeBotUpdates) Begin(updateID int) {
+	// Ensure secure access
+	sbus.mu.Lock()
+	defer sbus.mu.Unlock()
+
+	sbus.pending[updateID] = struct{}{}
+}
+
+// Marks update processed and its message in chat handled
+// if message ID is set
+func (sbus *SafeBotUpdates) Finish(updateID int, cid int64, msgID int) {
+	// Ensure secure access
+	sbus.mu.Lock()
+	defer sbus.mu.Unlock()
+
+	delete(sbus.pending, updateID)
+	sbus.LastUpdateID = max(sbus.LastUpdateID, updateID)
+	if msgID == 0 {
+		return
+	}
+
+	remember(sbus.Handled, cid, msgID)
+}
+
+// Marks message in chat added to history before adding it,
+// reports false if it already was (redelivered after crash)
+func (sbus *SafeBotUpdates) Record(cid int64, msgID int) bool {
+	// Ensure secure access
+	sbus.mu.Lock()
+	defer sbus.mu.Unlock()
+
+	if slices.Contains(sbus.Recorded[cid], msgID) {
+		return false
+	}
+	remember(sbus.Recorded, cid, msgID)
+	return true
+}
+
+// Remembers message ID in chat, forgets the oldest over cap
+func remember(ids map[int64][]int, cid int64, msgID int) {
+	chatIDs := append(ids[cid], msgID)
+	if len(chatIDs) > handledCap {
+		chatIDs = chatIDs[len(chatIDs)-handledCap:]
+	}
+	ids[cid] = chatIDs
+}
+
+// Reports if message in chat was already handled
+func (sbus *SafeBotUpdates) IsHandled(cid int64, msgID int) bool {
+	// Ensure secure access
+	sbus.mu.Lock()
+	defer sbus.mu.Unlock()
+
+	return slices.Contains(sbus.Handled[cid], msgID)
+}
+
+// Gets the last update ID safe to skip after restart:
+// the one before the earliest update in progress if any (lock held)
+func (sbus *SafeBotUpdates) committed() int {
+	committed := sbus.LastUpdateID
+	for updateID := range sbus.pending {
+		committed = min(committed, updateID-1)
+	}
+	return committed
+}
@@ -2,6 +2,7 @@ package history
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"tg-handler/logging"
 )
@@ -39,6 +40,14 @@ var (
 	)
 )
 
+// CHAT KEY
+
+// Chat history key, forum topics have own histories
+type ChatKey struct {
+	ChatID   int64
+	ThreadID int // Forum topic, 0 if none
+}
+
 // CHAT HISTORY
 
 // Chat history consists from chat queue and reply chains.
@@ -67,6 +76,8 @@ func NewChatHistory(scq *SafeChatQueue) *ChatHistory {
 type SafeChatQueue struct {
 	mu        sync.RWMutex
 	ChatQueue ChatQueue
+	Language  string         // Dominant ISO 639-1 code, empty if unknown
+	languages []lineLanguage // Of recent lines, not persisted
 
 	IsShared bool
 }
@@ -122,6 +133,58 @@ func (scq *SafeChatQueue) Get(
 	return scq.ChatQueue.get(lim, logger)
 }
 
+// Gets dominant language of chat queue
+func (scq *SafeChatQueue) GetLanguage() string {
+	// Ensure secure access
+	scq.mu.RLock()
+	defer scq.mu.RUnlock()
+
+	return scq.Language
+}
+
+// Language of chat queue line by message ID
+type lineLanguage struct {
+	msgID    int
+	language string
+}
+
+// Adds language of new message line once (queue may be shared by bots)
+// keeping the last ones within limit, updates dominant language
+// of chat queue from them and gets it
+func (scq *SafeChatQueue) AddLanguage(
+	msgID int, language string, lim int,
+) string {
+	// Ensure secure access
+	scq.mu.Lock()
+	defer scq.mu.Unlock()
+
+	// Add line language unless added by other bot
+	added := slices.ContainsFunc(scq.languages, func(l lineLanguage) bool {
+		return l.msgID == msgID
+	})
+	if !added {
+		scq.languages = append(scq.languages, lineLanguage{msgID, language})
+	}
+	if over := len(scq.languages) - lim; lim > 0 && over > 0 {
+		scq.languages = slices.Delete(scq.languages, 0, over)
+	}
+
+	// Count languages, the first one to lead dominates
+	var (
+		counts   = make(map[string]int)
+		dominant string
+	)
+	for _, l := range scq.languages {
+		counts[l.language]++
+		if counts[l.language] > counts[dominant] {
+			dominant = l.language
+		}
+	}
+
+	scq.Language = dominant
+	return dominant
+}
+
 // Gets chain from reply chains with limit
 func (src *SafeReplyChains) Get(
 	lc LineChain, lim int, logger *logging.Logger,
@@ -141,11 +141,13 @@ func (h *History) collectCleanJobs(logger *logging.Logger) []CleanJob {
 	)
 
 	// Add SHARED chat queues to jobs
-	for _, scq := range scqs {
+	scqs.mu.RLock()
+	for _, scq := range scqs.Queues {
 		jobs = append(jobs, CleanJob{
 			ChatQueue: scq,
 		})
 	}
+	scqs.mu.RUnlock()
 
 	// Add LOCAL chat queues & reply chains to jobs
 	bots.mu.RLock()
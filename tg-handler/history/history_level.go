@@ -22,8 +22,8 @@ var (
 // History consists from bot histories, bot-agnostic shared queues.
 // No pointer swap occures after initialization, no mutex needed.
 type History struct {
-	Bots             *SafeBotsHistory // Read-only (secured inside)
-	SharedChatQueues SharedChatQueues // Read-only
+	Bots             *SafeBotsHistory  // Read-only (secured inside)
+	SharedChatQueues *SharedChatQueues // Read-only (secured inside)
 }
 
 func NewHistory(cids []int64) *History {
@@ -49,17 +49,23 @@ func NewSafeBotsHistory() *SafeBotsHistory {
 // Bot data storage
 type BotsHistory map[string]*BotData
 
-// Shared chat queues for all allowed public chats,
+// Shared chat queues for all allowed public chats and their topics,
 // implicitly used to set chat queue on chat level if public
 // to avoid memory duplication and preserve simplicity for bots.
-type SharedChatQueues map[int64]*SafeChatQueue
+// Topic queues are created on first use, so mutex needed.
+type SharedChatQueues struct {
+	mu     sync.RWMutex
+	Queues map[ChatKey]*SafeChatQueue // Preinit for allowed chats
+}
 
-func NewSharedChatQueues(cids []int64) SharedChatQueues {
-	cq := make(SharedChatQueues, len(cids))
+func NewSharedChatQueues(cids []int64) *SharedChatQueues {
+	scqs := &SharedChatQueues{
+		Queues: make(map[ChatKey]*SafeChatQueue, len(cids)),
+	}
 	for _, cid := range cids {
-		cq[cid] = NewSafeChatQueue(true)
+		scqs.Queues[ChatKey{ChatID: cid}] = NewSafeChatQueue(true)
 	}
-	return cq
+	return scqs
 }
 
 // UNSAFE! Loads history or panics
@@ -93,7 +99,7 @@ func MustLoadHistory(
 		logger.Error(
 			errMsg,
 			logging.Err(
-				fmt.Errorf("%w: v", errUnmarshalFailed, err),
+				fmt.Errorf("%w: %v", errUnmarshalFailed, err),
 			),
 		)
 
@@ -146,6 +152,50 @@ func (sbh *SafeBotsHistory) init(botName string) *BotData {
 	return botData
 }
 
+// Gets shared chat queue, created for topic of allowed chat,
+// nil if chat is not allowed
+func (scqs *SharedChatQueues) Get(key ChatKey) *SafeChatQueue {
+	// Happy path: return existing shared chat queue
+	if scq, ok := scqs.get(key); ok {
+		return scq
+	}
+
+	// Unhappy path: return new topic queue
+	return scqs.init(key)
+}
+
+// Return existing shared chat queue with status
+func (scqs *SharedChatQueues) get(key ChatKey) (*SafeChatQueue, bool) {
+	// Ensure secure access
+	scqs.mu.RLock()
+	defer scqs.mu.RUnlock()
+
+	scq, ok := scqs.Queues[key]
+	return scq, ok
+}
+
+// Create new topic queue if chat is allowed
+func (scqs *SharedChatQueues) init(key ChatKey) *SafeChatQueue {
+	// Ensure secure access
+	scqs.mu.Lock()
+	defer scqs.mu.Unlock()
+
+	// Double check if init after lock release
+	if scq, ok := scqs.Queues[key]; ok {
+		return scq
+	}
+
+	// Check if chat is allowed
+	if _, ok := scqs.Queues[ChatKey{ChatID: key.ChatID}]; !ok {
+		return nil
+	}
+
+	// Return new topic queue
+	scq := NewSafeChatQueue(true)
+	scqs.Queues[key] = scq
+	return scq
+}
+
 // Locks history in cascade
 func (h *History) lock() {
 	var (
@@ -154,7 +204,8 @@ func (h *History) lock() {
 	)
 
 	// Firstly lock SHARED chat queues
-	for _, scq := range scqs {
+	scqs.mu.Lock()
+	for _, scq := range scqs.Queues {
 		scq.mu.Lock()
 	}
 
@@ -164,9 +215,11 @@ func (h *History) lock() {
 		var (
 			history  = botData.History
 			contacts = botData.Contacts
+			updates  = botData.Updates
 		)
 		history.mu.Lock()
 		contacts.mu.Lock()
+		updates.mu.Lock()
 
 		for _, sch := range history.History {
 			var (
@@ -193,9 +246,10 @@ func (h *History) unlock() {
 	)
 
 	// Firstly lock SHARED chat queues
-	for _, scq := range scqs {
+	for _, scq := range scqs.Queues {
 		scq.mu.Unlock()
 	}
+	scqs.mu.Unlock()
 
 	// Secondly lock LOCAL chat queues and reply chains
 	bots.mu.Unlock()
@@ -203,9 +257,11 @@ func (h *History) unlock() {
 		var (
 			history  = botData.History
 			contacts = botData.Contacts
+			updates  = botData.Updates
 		)
 		history.mu.Unlock()
 		contacts.mu.Unlock()
+		updates.mu.Unlock()
 
 		for _, sch := range history.History {
 			var (
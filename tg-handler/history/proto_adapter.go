@@ -17,38 +17,63 @@ func (h *History) toProto() *pb.RootHistory {
 		Bots:         make(map[string]*pb.BotData),
 	}
 
-	// Snapshot Shared Queues
-	for cid, scq := range h.SharedChatQueues {
-		root.SharedQueues[cid] = chatQueueToProto(scq.ChatQueue)
+	// Snapshot Shared Queues, topics apart
+	for key, scq := range h.SharedChatQueues.Queues {
+		pQueue := chatQueueToProto(scq.ChatQueue, scq.Language)
+		if key.ThreadID == 0 {
+			root.SharedQueues[key.ChatID] = pQueue
+			continue
+		}
+		root.SharedTopicQueues = append(
+			root.SharedTopicQueues, &pb.TopicQueue{
+				ChatId:   key.ChatID,
+				ThreadId: int32(key.ThreadID),
+				Queue:    pQueue,
+			},
+		)
 	}
 
 	// Snapshot Bots
 	for name, botData := range h.Bots.History {
 		pbBot := &pb.BotData{
-			Chats:    make(map[int64]*pb.ChatHistory),
-			Contacts: make(map[string]*pb.BotContact),
+			Chats:        make(map[int64]*pb.ChatHistory),
+			Contacts:     make(map[string]*pb.BotContact),
+			LastUpdateId: int64(botData.Updates.committed()),
+			Handled:      handledToProto(botData.Updates.Handled),
+			Recorded:     handledToProto(botData.Updates.Recorded),
 		}
 
 		// Contacts
 		for user, c := range botData.Contacts.Contacts {
 			pbBot.Contacts[user] = &pb.BotContact{
-				Carma: int32(c.Carma),
-				Tags:  c.Tags.Serialize(),
+				Carma:    int32(c.Carma),
+				Tags:     c.Tags.Serialize(),
+				Language: c.Language,
 			}
 		}
 
-		// Chat Histories
-		for cid, ch := range botData.History.History {
+		// Chat Histories, topics apart
+		for key, ch := range botData.History.History {
 			pbChat := &pb.ChatHistory{
 				ReplyChains: replyChainsToProto(ch.ReplyChains.ReplyChains),
 			}
 
 			// KEY LOGIC: If shared, do not save local_queue
 			if !ch.ChatQueue.IsShared {
-				pbChat.LocalQueue = chatQueueToProto(ch.ChatQueue.ChatQueue)
+				pbChat.LocalQueue = chatQueueToProto(
+					ch.ChatQueue.ChatQueue, ch.ChatQueue.Language,
+				)
 			}
 
-			pbBot.Chats[cid] = pbChat
+			if key.ThreadID == 0 {
+				pbBot.Chats[key.ChatID] = pbChat
+				continue
+			}
+			pbBot.Topics = append(pbBot.Topics, &pb.TopicHistory{
+				ChatId:   key.ChatID,
+				ThreadId: int32(key.ThreadID),
+				History:  pbChat,
+			})
 		}
 		root.Bots[name] = pbBot
 	}
@@ -59,19 +84,28 @@ func (h *History) toProto() *pb.RootHistory {
 // Convert Proto -> Go internal
 func fromProto(p *pb.RootHistory, cids []int64) *History {
 	h := NewHistory(cids) // Helper to init empty maps
+	queues := h.SharedChatQueues.Queues
 
 	// Load Shared Queues
 	// Overwrite empty ones created by NewHistory or fill new
-	for cid, pQueue := range p.SharedQueues {
+	loadQueue := func(key ChatKey, pQueue *pb.ChatQueue) {
 		// Check if this CID is allowed
-		if _, isAllowed := h.SharedChatQueues[cid]; !isAllowed {
+		chatKey := ChatKey{ChatID: key.ChatID}
+		if _, isAllowed := queues[chatKey]; !isAllowed {
 			// Skip loading history for chats removed from config
-			continue
+			return
 		}
 
 		scq := NewSafeChatQueue(true)
 		scq.ChatQueue = protoToChatQueue(pQueue)
-		h.SharedChatQueues[cid] = scq
+		scq.Language = pQueue.GetLanguage()
+		queues[key] = scq
+	}
+	for cid, pQueue := range p.SharedQueues {
+		loadQueue(ChatKey{ChatID: cid}, pQueue)
+	}
+	for _, pTopic := range p.SharedTopicQueues {
+		loadQueue(topicKey(pTopic.ChatId, pTopic.ThreadId), pTopic.Queue)
 	}
 
 	// Load Bots
@@ -81,38 +115,31 @@ func fromProto(p *pb.RootHistory, cids []int64) *History {
 		// Contacts
 		for user, pCont := range pBot.Contacts {
 			botData.Contacts.Contacts[user] = BotContact{
-				Carma: carma.Carma(pCont.Carma),
-				Tags:  tags.DeserializeTags(pCont.Tags),
+				Carma:    carma.Carma(pCont.Carma),
+				Tags:     tags.DeserializeTags(pCont.Tags),
+				Language: pCont.Language,
 			}
 		}
 
+		// Updates
+		botData.Updates.LastUpdateID = int(pBot.LastUpdateId)
+		botData.Updates.Handled = protoToHandled(pBot.Handled)
+		botData.Updates.Recorded = protoToHandled(pBot.Recorded)
+
 		// Histories
+		var (
+			history = botData.History.History
+			shared  = h.SharedChatQueues
+		)
 		for cid, pChat := range pBot.Chats {
-			// Restore Reply Chains
-			replyChains := NewSafeReplyChains()
-			replyChains.ReplyChains = protoToReplyChains(pChat.ReplyChains)
-
-			// Restore Chat Queue
-			var scq *SafeChatQueue
-
-			if pChat.LocalQueue != nil {
-				// Case A: It was saved as local
-				scq = NewSafeChatQueue(false)
-				scq.ChatQueue = protoToChatQueue(pChat.LocalQueue)
-			} else {
-				// Case B: It is shared, link to the SharedChatQueues
-				if shared, exists := h.SharedChatQueues[cid]; exists {
-					scq = shared
-				} else {
-					// Fallback if shared queue missing (shouldn't happen)
-					scq = NewSafeChatQueue(true)
-				}
-			}
-
-			botData.History.History[cid] = &ChatHistory{
-				ChatQueue:   scq,
-				ReplyChains: replyChains,
-			}
+			key := ChatKey{ChatID: cid}
+			history[key] = protoToChatHistory(pChat, shared.Get(key))
+		}
+		for _, pTopic := range pBot.Topics {
+			key := topicKey(pTopic.ChatId, pTopic.ThreadId)
+			history[key] = protoToChatHistory(
+				pTopic.History, shared.Get(key),
+			)
 		}
 		h.Bots.History[name] = botData
 	}
@@ -122,8 +149,47 @@ func fromProto(p *pb.RootHistory, cids []int64) *History {
 
 // --- HELPERS ---
 
-func chatQueueToProto(cq ChatQueue) *pb.ChatQueue {
-	pq := &pb.ChatQueue{Messages: make([]*pb.MessageEntry, len(cq))}
+func topicKey(chatID int64, threadID int32) ChatKey {
+	return ChatKey{ChatID: chatID, ThreadID: int(threadID)}
+}
+
+func protoToChatHistory(
+	pChat *pb.ChatHistory,
+	shared *SafeChatQueue, // Linked if chat queue is shared
+) *ChatHistory {
+	// Restore Reply Chains
+	replyChains := NewSafeReplyChains()
+	replyChains.ReplyChains = protoToReplyChains(pChat.GetReplyChains())
+
+	// Restore Chat Queue
+	var scq *SafeChatQueue
+
+	if pChat.GetLocalQueue() != nil {
+		// Case A: It was saved as local
+		scq = NewSafeChatQueue(false)
+		scq.ChatQueue = protoToChatQueue(pChat.LocalQueue)
+		scq.Language = pChat.LocalQueue.GetLanguage()
+	} else {
+		// Case B: It is shared, link to the SharedChatQueues
+		if shared != nil {
+			scq = shared
+		} else {
+			// Fallback if shared queue missing (shouldn't happen)
+			scq = NewSafeChatQueue(true)
+		}
+	}
+
+	return &ChatHistory{
+		ChatQueue:   scq,
+		ReplyChains: replyChains,
+	}
+}
+
+func chatQueueToProto(cq ChatQueue, language string) *pb.ChatQueue {
+	pq := &pb.ChatQueue{
+		Messages: make([]*pb.MessageEntry, len(cq)),
+		Language: language,
+	}
 	for i, m := range cq {
 		pq.Messages[i] = &pb.MessageEntry{
 			Line:      m.Line,
@@ -171,3 +237,27 @@ func protoToReplyChains(prc *pb.ReplyChains) ReplyChains {
 	}
 	return rc
 }
+
+func handledToProto(handled map[int64][]int) map[int64]*pb.MessageIDs {
+	ph := make(map[int64]*pb.MessageIDs, len(handled))
+	for cid, ids := range handled {
+		pIDs := &pb.MessageIDs{Ids: make([]int64, len(ids))}
+		for i, id := range ids {
+			pIDs.Ids[i] = int64(id)
+		}
+		ph[cid] = pIDs
+	}
+	return ph
+}
+
+func protoToHandled(ph map[int64]*pb.MessageIDs) map[int64][]int {
+	handled := make(map[int64][]int, len(ph))
+	for cid, pIDs := range ph {
+		ids := make([]int, len(pIDs.GetIds()))
+		for i, id := range pIDs.GetIds() {
+			ids[i] = int(id)
+		}
+		handled[cid] = ids
+	}
+	return handled
+}
@@ -0,0 +1,90 @@
+package langdetect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pemistahl/lingua-go"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Language of replies generated by model
+const English = "en"
+
+// Detector errors
+var (
+	errUnknownLanguage = errors.New("unknown language code")
+	errTooFewLanguages = errors.New("less than 2 languages to detect")
+	errDistanceOOB     = errors.New("min distance is out of bounds 0-0.99")
+)
+
+// Language detector shared by bots
+type Detector struct {
+	detector lingua.LanguageDetector
+}
+
+// Constructs detector from settings or panics
+func MustNew(
+	settings *conf.LanguageSettings, logger *logging.Logger,
+) *Detector {
+	const errMsg = "failed to create language detector"
+
+	// Get languages, all if not set
+	builder := lingua.NewLanguageDetectorBuilder()
+	var configured lingua.LanguageDetectorBuilder
+	if len(settings.Languages) == 0 {
+		configured = builder.FromAllLanguages()
+	} else {
+		if len(settings.Languages) < 2 {
+			logger.Panic(errMsg, logging.Err(errTooFewLanguages))
+		}
+		codes := make([]lingua.IsoCode639_1, 0, len(settings.Languages))
+		for _, code := range settings.Languages {
+			isoCode := lingua.GetIsoCode639_1FromValue(code)
+			if isoCode == lingua.UnknownIsoCode639_1 {
+				logger.Panic(errMsg, logging.Err(
+					fmt.Errorf("%w: %v", errUnknownLanguage, code),
+				))
+			}
+			codes = append(codes, isoCode)
+		}
+		configured = builder.FromIsoCodes639_1(codes...)
+	}
+
+	// Set accuracy
+	if settings.MinDistance < 0 || settings.MinDistance >= 0.99 {
+		logger.Panic(errMsg, logging.Err(errDistanceOOB))
+	}
+	configured = configured.WithMinimumRelativeDistance(
+		settings.MinDistance,
+	)
+	if settings.LowAccuracy {
+		configured = configured.WithLowAccuracyMode()
+	}
+
+	return &Detector{
+		detector: configured.Build(),
+	}
+}
+
+// Detects language of text, empty if not reliable
+func (d *Detector) Detect(text string) string {
+	language, ok := d.detector.DetectLanguageOf(text)
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(language.IsoCode639_1().String())
+}
+
+// Gets English name of language by code, English if unknown
+func Name(code string) string {
+	isoCode := lingua.GetIsoCode639_1FromValue(code)
+	if isoCode == lingua.UnknownIsoCode639_1 {
+		return lingua.English.String()
+	}
+
+	return lingua.GetLanguageFromIsoCode639_1(isoCode).String()
+}
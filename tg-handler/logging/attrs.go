@@ -21,6 +21,10 @@ func ChatID(id int64) slog.Attr {
 	return slog.Int64("chat_id", id)
 }
 
+func ThreadID(id int) slog.Attr {
+	return slog.Int("thread_id", id)
+}
+
 func UserName(name string) slog.Attr {
 	return slog.String("user_name", name)
 }
@@ -93,6 +97,62 @@ func Candidate(s string) slog.Attr {
 	return slog.String("candidate", s)
 }
 
+func Language(code string) slog.Attr {
+	return slog.String("language", code)
+}
+
+func Critique(text string) slog.Attr {
+	return slog.String("critique", text)
+}
+
+func Before(text string) slog.Attr {
+	return slog.String("before", text)
+}
+
+func After(text string) slog.Attr {
+	return slog.String("after", text)
+}
+
+func Similarity(ratio float64) slog.Attr {
+	return slog.Float64("similarity", ratio)
+}
+
+func Dropped(n int) slog.Attr {
+	return slog.Int("dropped", n)
+}
+
+func Step(name string) slog.Attr {
+	return slog.String("step", name)
+}
+
+func StepOutput(text string) slog.Attr {
+	return slog.String("step_output", text)
+}
+
+func Round(n int) slog.Attr {
+	return slog.Int("round", n)
+}
+
+func CandidateNum(n int) slog.Attr {
+	return slog.Int("candidate_num", n)
+}
+
+func Rating(n int) slog.Attr {
+	return slog.Int("rating", n)
+}
+
+func Vote(n int) slog.Attr {
+	return slog.Int("vote", n)
+}
+
+func VoteDistribution(s string) slog.Attr {
+	return slog.String("vote_distribution", s)
+}
+
+func Ranking(s string) slog.Attr {
+	return slog.String("ranking", s)
+}
+
 func Tags(s string) slog.Attr {
 	return slog.String("tags", s)
 }
@@ -101,14 +161,50 @@ func CarmaUpdate(s string) slog.Attr {
 	return slog.String("carma_update", s)
 }
 
+func Rationale(s string) slog.Attr {
+	return slog.String("rationale", s)
+}
+
+// --- SCHEDULER ---
+
+func Priority(s string) slog.Attr {
+	return slog.String("priority", s)
+}
+
+func InFlight(n int) slog.Attr {
+	return slog.Int("in_flight", n)
+}
+
+func QueuedReply(n int) slog.Attr {
+	return slog.Int("queued_reply", n)
+}
+
+func QueuedReflect(n int) slog.Attr {
+	return slog.Int("queued_reflect", n)
+}
+
 // --- OLLAMA ---
 
+func ModelName(s string) slog.Attr {
+	return slog.String("model", s)
+}
+
 func RawResponse(s string) slog.Attr {
 	return slog.String("raw_response", s)
 }
 
+// --- REFLECTION ---
+
+func QueueLen(n int) slog.Attr {
+	return slog.Int("queue_len", n)
+}
+
 // --- MESSAGING ---
 
 func Signal(s string) slog.Attr {
 	return slog.String("signal", s)
 }
+
+func Attempt(n int) slog.Attr {
+	return slog.Int("attempt", n)
+}
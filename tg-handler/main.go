@@ -7,11 +7,14 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"tg-handler/bot"
 	"tg-handler/conf"
 	"tg-handler/history"
+	"tg-handler/langdetect"
 	"tg-handler/logging"
+	"tg-handler/model"
 	"tg-handler/secret"
 )
 
@@ -77,11 +80,23 @@ func startBots(
 		)
 	})
 
+	// Start LLM request scheduler monitor
+	scheduler := model.NewScheduler(&iConf.SchedulerSettings, logger)
+	wg.Go(func() {
+		scheduler.Monitor(
+			ctx, time.Duration(iConf.SchedulerSettings.MetricsInterval),
+		)
+	})
+
+	// Get language detector, models are loaded on first use
+	detector := langdetect.MustNew(&iConf.LanguageSettings, logger)
+
 	// Start all bots
 	for _, apiKey := range apiKeys {
 		wg.Go(func() {
 			bot := bot.New(
-				apiKey, iConf, history, updateCh, &wg, logger,
+				apiKey, iConf, history, scheduler, detector,
+				updateCh, &wg, logger,
 			)
 			bot.Start(ctx)
 		})
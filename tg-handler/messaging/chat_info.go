@@ -10,6 +10,7 @@ import (
 
 type ChatInfo struct {
 	ID        int64
+	ThreadID  int // Forum topic, 0 if none
 	Title     string
 	History   *history.ChatHistory
 	IsAllowed bool
@@ -18,13 +19,16 @@ type ChatInfo struct {
 }
 
 // Constructs chat info by following bot procedure
-// on how to validate chat ID. Reuses chat queues
-// for public chats.
+// on how to validate chat ID and topic. Reuses chat queues
+// for public chats, forum topics have own ones.
+// Denied chats and topics get no history.
 func NewChatInfo(
 	m *MessageInfo,
+	threadID int,
+	topic string, // Forum topic name, empty if unknown
 	sbh *history.SafeBotHistory,
-	shared history.SharedChatQueues,
-	validateChatID func(int64) bool,
+	shared *history.SharedChatQueues,
+	validateChat func(int64, int) bool,
 ) *ChatInfo {
 	// Get message vars
 	var (
@@ -36,41 +40,51 @@ func NewChatInfo(
 	// Get chat vars
 	var (
 		cid       = chat.ID
+		key       = history.ChatKey{ChatID: cid, ThreadID: threadID}
 		isPrivate = chat.IsPrivate()
 	)
 
-	// Process message based on sender
-	var isAllowed bool
+	// Validate ordinary message, admin one is allowed
+	isAllowed := isFromAdmin || validateChat(cid, threadID)
+	chatInfo := &ChatInfo{
+		ID:        cid,
+		ThreadID:  threadID,
+		Title:     getChatTitle(chat, sender, isPrivate, topic),
+		IsAllowed: isAllowed,
+		LastMsg:   m,
+	}
+
+	// Leave denied chat without history
+	if !isAllowed {
+		return chatInfo
+	}
+
+	// Use new chat queue for admin, shared one otherwise
 	var safeChatQueue *history.SafeChatQueue
-	if isFromAdmin { // Message from admin: allowed, new chat queue
-		isAllowed = true
-	} else { // Ordinary message: validated, shared chat queue
-		isAllowed = validateChatID(cid)
-		safeChatQueue = shared[cid]
+	if !isFromAdmin {
+		safeChatQueue = shared.Get(key)
 	}
 
 	// Get history by passing nil/shared safe chat queue
 	// for admin chats and public chats respectively,
 	// nil means new safe chat queue will be created
-	SafeChatHistory, _ := sbh.Get(cid, safeChatQueue)
+	chatInfo.History, _ = sbh.Get(key, safeChatQueue)
 
-	return &ChatInfo{
-		ID:        cid,
-		Title:     getChatTitle(chat, sender, isPrivate),
-		History:   SafeChatHistory,
-		IsAllowed: isAllowed,
-		LastMsg:   m,
-	}
+	return chatInfo
 }
 
-// Gets chat title for any chat
+// Gets chat title for any chat, with topic name if known
 func getChatTitle(
 	chat *tg.Chat,
 	sender string,
 	isPrivate bool,
+	topic string,
 ) string {
 	if isPrivate {
 		return fmt.Sprintf("%s's private", sender)
 	}
+	if topic != "" {
+		return fmt.Sprintf("%s / %s", chat.Title, topic)
+	}
 	return chat.Title
 }
@@ -0,0 +1,63 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/conf"
+)
+
+// Gets compact placeholder of non-text message
+// (e.g. "[sticker 😂]", "[poll: Pizza or sushi?]")
+// if its kind is enabled in settings, empty otherwise
+func Describe(msg *tg.Message, settings *conf.Descriptions) string {
+	switch {
+	case msg.Sticker != nil && settings.Stickers:
+		return getPlaceholder("sticker", msg.Sticker.Emoji)
+	case msg.Poll != nil && settings.Polls:
+		return getPlaceholder("poll:", msg.Poll.Question)
+	case msg.Venue != nil && settings.Locations:
+		return getPlaceholder(
+			"venue:", joinNonEmpty(", ", msg.Venue.Title, msg.Venue.Address),
+		)
+	case msg.Location != nil && settings.Locations:
+		return getPlaceholder("location", fmt.Sprintf(
+			"%.5f, %.5f", msg.Location.Latitude, msg.Location.Longitude,
+		))
+	case msg.Contact != nil && settings.Contacts:
+		return getPlaceholder("contact:", joinNonEmpty(
+			" ", msg.Contact.FirstName, msg.Contact.LastName,
+		))
+	case msg.Dice != nil && settings.Dice:
+		return getPlaceholder(
+			"dice", fmt.Sprintf("%s %d", msg.Dice.Emoji, msg.Dice.Value),
+		)
+	case msg.Animation != nil && settings.Animations: // Before document
+		return getPlaceholder("GIF", "")
+	case msg.Document != nil && settings.Documents:
+		return getPlaceholder("document:", msg.Document.FileName)
+	default:
+		return ""
+	}
+}
+
+// Gets "[kind detail]" placeholder, "[kind]" if detail is empty
+func getPlaceholder(kind string, detail string) string {
+	if detail == "" {
+		return "[" + strings.TrimSuffix(kind, ":") + "]"
+	}
+	return "[" + kind + " " + detail + "]"
+}
+
+// Joins non-empty strings with separator
+func joinNonEmpty(sep string, s ...string) string {
+	parts := make([]string, 0, len(s))
+	for _, part := range s {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, sep)
+}
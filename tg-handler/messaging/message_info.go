@@ -3,6 +3,7 @@ package messaging
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -16,28 +17,43 @@ var (
 	errMsgEmptyText   = errors.New("empty text of message")
 )
 
+// Media kinds
+const (
+	mediaPhoto = "photo"
+	mediaVoice = "voice"
+	mediaAudio = "audio"
+)
+
 // Recursive type.
 // Provides Line(), PrevLine() methods to construct reply chain.
 // Provides ID() and Sender() as methods.
 type MessageInfo struct {
 	ID           int    // Message identifier
 	sender       string // UserName | FirstName (+LastName)
+	text         string // Text | Caption
 	line         string // "Sender: text"
 	IsTriggering bool   // Is message meant to be replied
 	IsFromAdmin  bool   // Is message meant to be queued privately
+	HasImage     bool   // Photo or image document
+	HasVoice     bool   // Voice note or audio
+	IsDescribed  bool   // Non-text message rendered to placeholder
+	media        string // Media placeholder kind, empty if none
 	Chat         *tg.Chat
 	prevMsg      *MessageInfo // Previous message info
 }
 
 // Constructs message info by following bot procedures
-// on how to detect admin/reply/mentions; modify mentions.
+// on how to detect admin/reply/discussion/mentions; modify mentions;
+// describe non-text messages.
 func NewMessageInfo(
 	bot *tg.BotAPI,
 	msg *tg.Message,
 	detectAdmin func(*tg.Message, string) bool,
 	detectReply func(*tg.Message) bool,
+	detectDiscussion func(*tg.Message) bool,
 	detectMentions func(string) bool,
 	modifyMentions func(string) string,
+	describe func(*tg.Message) string,
 	level int,
 ) (*MessageInfo, error) {
 	// Handle nil and too deep recursion
@@ -45,11 +61,16 @@ func NewMessageInfo(
 		return nil, nil
 	}
 
-	// Get sender and text
+	// Get sender, text and media kind or placeholder of other message
 	var (
-		sender = getSender(msg)
-		text   = getText(msg)
+		sender      = getSender(msg)
+		text        = getText(msg)
+		media       = getMedia(msg)
+		placeholder string
 	)
+	if media == "" {
+		placeholder = describe(msg)
+	}
 
 	// Handle empty sender and text
 	if sender == "" && text == "" {
@@ -60,17 +81,20 @@ func NewMessageInfo(
 	if sender == "" {
 		return nil, errMsgEmptySender
 	}
-	if text == "" {
+	if text == "" && media == "" && placeholder == "" {
 		return nil, errMsgEmptyText
 	}
 
 	// Get basic info
 	var (
-		isFromAdmin = detectAdmin(msg, sender)
-		isReplied   = detectReply(msg)
-		isMentioned = detectMentions(text)
+		isFromAdmin  = detectAdmin(msg, sender)
+		isReplied    = detectReply(msg)
+		isDiscussion = detectDiscussion(msg)
+		isMentioned  = detectMentions(text)
 	)
 
+	isTriggering := isFromAdmin || isReplied || isDiscussion || isMentioned
+
 	// Modify bot mentions if they exist
 	if isMentioned {
 		text = modifyMentions(text)
@@ -81,8 +105,10 @@ func NewMessageInfo(
 		bot, msg.ReplyToMessage,
 		detectAdmin,
 		detectReply,
+		detectDiscussion,
 		detectMentions,
 		modifyMentions,
+		describe,
 		level+1,
 	)
 
@@ -90,9 +116,14 @@ func NewMessageInfo(
 		Chat:         msg.Chat,
 		ID:           msg.MessageID,
 		sender:       sender,
-		line:         getLine(sender, text),
-		IsTriggering: isFromAdmin || isReplied || isMentioned,
+		text:         text,
+		line:         getLine(sender, getLineText(media, placeholder, text)),
+		IsTriggering: isTriggering,
 		IsFromAdmin:  isFromAdmin,
+		HasImage:     media == mediaPhoto,
+		HasVoice:     media == mediaVoice || media == mediaAudio,
+		IsDescribed:  placeholder != "",
+		media:        media,
 		prevMsg:      prevMsg,
 	}, nil
 }
@@ -111,16 +142,57 @@ func (m *MessageInfo) PrevLine() string {
 	return ""
 }
 
+// Sets image description to line
+func (m *MessageInfo) DescribeImage(description string) {
+	m.line = getLine(m.sender, getMediaText(m.media, description, m.text))
+}
+
+// Sets voice transcript to text followed by caption, and to line
+func (m *MessageInfo) Transcribe(transcript string) {
+	if m.text != "" {
+		transcript += " " + m.text
+	}
+	m.text = transcript
+	m.line = getLine(m.sender, getMediaText(m.media, "", m.text))
+}
+
+// Text exposed
+func (m *MessageInfo) Text() string {
+	return m.text
+}
+
 // Sender exposed
 func (m *MessageInfo) Sender() string {
 	return m.sender
 }
 
-// Gets UserName | FirstName (+LastName)
+// Gets sender chat identity if set (channels, anonymous admins,
+// automatic forwards), UserName | FirstName (+LastName) otherwise
 func getSender(msg *tg.Message) string {
+	if msg.SenderChat != nil {
+		return getChatSender(msg)
+	}
+	if msg.From == nil {
+		return ""
+	}
 	return msg.From.String()
 }
 
+// Gets signature of anonymous admin if set,
+// sender chat UserName | Title otherwise
+func getChatSender(msg *tg.Message) string {
+	chat := msg.SenderChat
+
+	isAnonymousAdmin := msg.Chat != nil && chat.ID == msg.Chat.ID
+	if isAnonymousAdmin && msg.AuthorSignature != "" {
+		return msg.AuthorSignature
+	}
+	if chat.UserName != "" {
+		return chat.UserName
+	}
+	return chat.Title
+}
+
 // Gets Text | Caption
 func getText(msg *tg.Message) (text string) {
 	if msg.Text != "" {
@@ -132,6 +204,57 @@ func getText(msg *tg.Message) (text string) {
 	return text
 }
 
+// Reports if message has photo or image document
+func HasImage(msg *tg.Message) bool {
+	if len(msg.Photo) > 0 {
+		return true
+	}
+	doc := msg.Document
+	return doc != nil && strings.HasPrefix(doc.MimeType, "image/")
+}
+
+// Gets media kind of message for placeholder, empty if none
+func getMedia(msg *tg.Message) string {
+	switch {
+	case HasImage(msg):
+		return mediaPhoto
+	case msg.Voice != nil:
+		return mediaVoice
+	case msg.Audio != nil:
+		return mediaAudio
+	default:
+		return ""
+	}
+}
+
+// Gets media text, placeholder of other message followed by text
+// if described, text otherwise
+func getLineText(media string, placeholder string, text string) string {
+	if placeholder == "" {
+		return getMediaText(media, "", text)
+	}
+	if text == "" {
+		return placeholder
+	}
+	return placeholder + " " + text
+}
+
+// Gets "[media: detail] text" if message has media, text otherwise
+func getMediaText(media string, detail string, text string) string {
+	if media == "" {
+		return text
+	}
+
+	placeholder := "[" + media + "]"
+	if detail != "" {
+		placeholder = "[" + media + ": " + detail + "]"
+	}
+	if text == "" {
+		return placeholder
+	}
+	return placeholder + " " + text
+}
+
 // Gets "Sender: text" message history representation
 func getLine(sender string, text string) string {
 	titleizer := cases.Title(language.English)
@@ -1,58 +1,205 @@
 package messaging
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"tg-handler/logging"
+	"tg-handler/outbox"
+	"tg-handler/outgoing"
+	"tg-handler/render"
 )
 
 // Messaging errors
 var (
 	errDirectReplyFailed   = errors.New("direct reply failed")
 	errIndirectReplyFailed = errors.New("indirect reply failed")
+	errRenderFailed        = errors.New("formatted text rejected")
+	errPartialReply        = errors.New("reply sent in part")
 )
 
-// Try to reply twice: with reply, with separate message
+// Partial delivery of split reply, the rest parts are to be retried
+type PartialError struct {
+	Index int      // First unsent part
+	Rest  []string // Unsent parts
+	err   error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf(
+		"%v: part %d not sent: %v", errPartialReply, e.Index+1, e.err,
+	)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.err
+}
+
+// Quoted user line limit in UTF-16 code units
+const maxQuoteLen = render.MaxLen / 4
+
+// Try to reply twice: with reply, with separate message,
+// unless the first error is permanent for chat.
+// Long text is split, the rest parts are sent as separate messages;
+// returned first message keeps the sent text for history,
+// unsent parts are reported with partial error.
+// Unsent parts of entry are sent as separate messages only.
 func Reply(
-	bot *tg.BotAPI, c *ChatInfo, text string,
+	ctx context.Context, sender *outgoing.Sender, e *outbox.Entry,
 	logger *logging.Logger,
-) *tg.Message {
+) (*tg.Message, error) {
 	// Set template (for replying already deleted messages)
-	const ReplyToDelT = "> '%s'\n\n%s"
-	// Set error messages
-	const (
-		errDirectMsg   = "direct reply failed"
-		errIndirectMsg = "indirect reply failed"
-	)
+	const ReplyToDelT = "> '%s'\n\n"
+	// Set error message
+	const errDirectMsg = "direct reply failed"
 
 	var (
-		msgID  = c.LastMsg.ID
-		chatID = c.ID
+		text   = e.Text
+		format = e.Format
 	)
 
-	// Get and set message config
-	m := tg.NewMessage(chatID, text)
-	m.ReplyToMessageID = msgID
+	// Get and set message target
+	t := target{
+		chatID:    e.ChatID,
+		threadID:  e.ThreadID,
+		replyToID: e.ReplyToID,
+	}
+
+	// Continue partial reply
+	if len(e.Rest) > 0 {
+		t.replyToID = 0
+		response, err := send(ctx, sender, t, e.Rest[0], format, logger)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errIndirectReplyFailed, err)
+		}
+		return sendRest(ctx, sender, t, &response, e.Rest, format, logger)
+	}
+
+	// Split text within limit
+	parts := split(text, render.MaxLen)
 
 	// Try to reply with reply
-	response, err := bot.Send(m)
+	response, err := send(ctx, sender, t, parts[0], format, logger)
+	if err != nil && (outgoing.IsPermanent(err) || ctx.Err() != nil) {
+		return nil, fmt.Errorf("%w: %w", errDirectReplyFailed, err)
+	}
 	if err != nil { // Try to reply with separate message
 		logger.Error(errDirectMsg, logging.Err(
 			fmt.Errorf("%w: %v", errDirectReplyFailed, err),
 		))
 
-		m.ReplyToMessageID = 0
-		m.Text = fmt.Sprintf(ReplyToDelT, c.LastMsg.Line(), text)
-		response, err = bot.Send(m)
+		// Split again reserving room for quote
+		t.replyToID = 0
+		quote := fmt.Sprintf(ReplyToDelT, quotedLine(e.UserLine))
+		parts = split(text, render.MaxLen-render.Len(quote))
+		response, err = send(
+			ctx, sender, t, quote+parts[0], format, logger,
+		)
 	}
 	if err != nil {
-		logger.Error(errIndirectMsg, logging.Err(
-			fmt.Errorf("%w: %v", errIndirectReplyFailed, err),
+		return nil, fmt.Errorf("%w: %w", errIndirectReplyFailed, err)
+	}
+
+	// Send the rest parts, keep the whole text if all sent
+	reply, err := sendRest(ctx, sender, t, &response, parts, format, logger)
+	if err == nil {
+		reply.Text = text
+	}
+	return reply, err
+}
+
+// Sends parts after the first sent one as separate messages,
+// first message keeps the sent text
+func sendRest(
+	ctx context.Context, sender *outgoing.Sender, t target,
+	first *tg.Message, parts []string, format string,
+	logger *logging.Logger,
+) (*tg.Message, error) {
+	t.replyToID = 0
+	for i := 1; i < len(parts); i++ {
+		_, err := send(ctx, sender, t, parts[i], format, logger)
+		if err != nil {
+			first.Text = strings.Join(parts[:i], "\n")
+			return first, &PartialError{Index: i, Rest: parts[i:], err: err}
+		}
+	}
+
+	first.Text = strings.Join(parts, "\n")
+	return first, nil
+}
+
+// Splits text within limit, keeps it whole if empty
+func split(text string, limit int) []string {
+	parts := render.Split(text, limit)
+	if len(parts) == 0 {
+		return []string{text}
+	}
+	return parts
+}
+
+// Gets user line cut to quote limit
+func quotedLine(line string) string {
+	if render.Len(line) <= maxQuoteLen {
+		return line
+	}
+	return render.Split(line, maxQuoteLen-1)[0] + "…"
+}
+
+// Sends text rendered in format, falls back to plain text
+// if Telegram fails to parse it
+func send(
+	ctx context.Context, sender *outgoing.Sender, t target,
+	text string, format string, logger *logging.Logger,
+) (tg.Message, error) {
+	const method = "sendMessage"
+
+	parseMode := render.ParseMode(format)
+	response, err := sender.Send(
+		ctx, t.chatID, method,
+		t.params(render.Render(text, format), parseMode), logger,
+	)
+	if err != nil && parseMode != "" && isParseError(err) {
+		logger.Error("sending as plain text", logging.Err(
+			fmt.Errorf("%w: %v", errRenderFailed, err),
 		))
+
+		response, err = sender.Send(
+			ctx, t.chatID, method, t.params(text, ""), logger,
+		)
 	}
 
-	return &response
+	return response, err
+}
+
+// Message destination
+type target struct {
+	chatID    int64
+	threadID  int // Forum topic, 0 if none
+	replyToID int // 0 for separate message
+}
+
+// Gets params addressing target
+func (t target) base() tg.Params {
+	params := make(tg.Params)
+	params.AddNonZero64("chat_id", t.chatID)
+	params.AddNonZero("message_thread_id", t.threadID)
+	params.AddNonZero("reply_to_message_id", t.replyToID)
+	return params
+}
+
+// Gets sendMessage params of text in parse mode
+func (t target) params(text string, parseMode string) tg.Params {
+	params := t.base()
+	params.AddNonEmpty("text", text)
+	params.AddNonEmpty("parse_mode", parseMode)
+	return params
+}
+
+// Reports if Telegram failed to parse formatted text
+func isParseError(err error) bool {
+	return strings.Contains(err.Error(), "can't parse entities")
 }
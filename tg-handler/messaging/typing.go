@@ -9,6 +9,7 @@ import (
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"tg-handler/logging"
+	"tg-handler/outgoing"
 )
 
 // Typing errors
@@ -16,9 +17,9 @@ var (
 	errSignalFailed = errors.New("signal request failed")
 )
 
-// Sends typing signal until context done
+// Sends typing signal until context done or permanent error
 func Type(
-	ctx context.Context, bot *tg.BotAPI, c *ChatInfo,
+	ctx context.Context, sender *outgoing.Sender, c *ChatInfo,
 	logger *logging.Logger,
 ) {
 	// Set constants
@@ -28,10 +29,16 @@ func Type(
 	)
 	logger = logger.With(logging.Signal(signal))
 
-	cid := c.ID
+	var (
+		cid      = c.ID
+		threadID = c.ThreadID
+	)
 
 	// Type right away
-	sendSignal(bot, cid, signal, logger)
+	err := sendSignal(ctx, sender, cid, threadID, signal, logger)
+	if outgoing.IsPermanent(err) {
+		return
+	}
 
 	// Set ticker with interval
 	t := time.NewTicker(interval)
@@ -41,7 +48,12 @@ func Type(
 	for {
 		select {
 		case <-t.C:
-			sendSignal(bot, cid, signal, logger)
+			err := sendSignal(
+				ctx, sender, cid, threadID, signal, logger,
+			)
+			if outgoing.IsPermanent(err) {
+				return
+			}
 		case <-ctx.Done():
 			logger.Debug("typing context done")
 			return
@@ -49,20 +61,24 @@ func Type(
 	}
 }
 
-// Sends signal via bot in specific chat
+// Sends signal via sender in specific chat and topic
 func sendSignal(
-	bot *tg.BotAPI, cid int64, signal string,
-	logger *logging.Logger,
-) {
+	ctx context.Context, sender *outgoing.Sender, cid int64,
+	threadID int, signal string, logger *logging.Logger,
+) error {
 	// Set error message
 	const errMsg = "signal send failed"
 
 	// Try to send signal
-	actConf := tg.NewChatAction(cid, signal)
-	_, err := bot.Request(actConf)
-	if err != nil {
+	params := make(tg.Params)
+	params.AddNonZero64("chat_id", cid)
+	params.AddNonZero("message_thread_id", threadID)
+	params.AddNonEmpty("action", signal)
+	err := sender.Request(ctx, cid, "sendChatAction", params, logger)
+	if err != nil && ctx.Err() == nil {
 		logger.Error(errMsg, logging.Err(
 			fmt.Errorf("%w: %v", errSignalFailed, err)),
 		)
 	}
+	return err
 }
@@ -0,0 +1,50 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/logging"
+	"tg-handler/outbox"
+	"tg-handler/outgoing"
+)
+
+// Voice errors
+var (
+	errVoiceReplyFailed = errors.New("voice reply failed")
+)
+
+// Replies with OGG/Opus audio of outbox entry text as voice note.
+// Returned message keeps the text for history.
+func ReplyVoice(
+	ctx context.Context, sender *outgoing.Sender, e *outbox.Entry,
+	audio []byte, logger *logging.Logger,
+) (*tg.Message, error) {
+	const method = "sendVoice"
+
+	// Get and set message target
+	t := target{
+		chatID:    e.ChatID,
+		threadID:  e.ThreadID,
+		replyToID: e.ReplyToID,
+	}
+
+	// Upload voice note
+	files := []tg.RequestFile{{
+		Name: "voice",
+		Data: tg.FileBytes{Name: "voice.ogg", Bytes: audio},
+	}}
+	response, err := sender.Upload(
+		ctx, t.chatID, method, t.base(), files, logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errVoiceReplyFailed, err)
+	}
+
+	// Keep the text
+	response.Text = e.Text
+	return &response, nil
+}
@@ -5,7 +5,14 @@ import (
 	"strings"
 )
 
-type Candidates []string
+// Candidate with model produced it
+type Candidate struct {
+	Text       string
+	Model      string
+	Repetition float64 // Selection penalty, 0 if fresh
+}
+
+type Candidates []Candidate
 
 // Candidates in human-readable format
 func (cs Candidates) String() (s string) {
@@ -13,14 +20,18 @@ func (cs Candidates) String() (s string) {
 
 	for i, candidate := range cs {
 		sb.WriteString(
-			fmt.Sprintf("%d) %s\n\n", i+1, candidate),
+			fmt.Sprintf("%d) %s\n\n", i+1, candidate.Text),
 		)
 	}
 
 	return sb.String()
 }
 
-// Value returned in case of tries exhaustion
-func (cs Candidates) Fallback() string {
-	return cs[0]
+// Candidate texts
+func (cs Candidates) Texts() []string {
+	texts := make([]string, 0, len(cs))
+	for _, candidate := range cs {
+		texts = append(texts, candidate.Text)
+	}
+	return texts
 }
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+	"strings"
+
+	"tg-handler/conf"
+	"tg-handler/denoising"
+	"tg-handler/logging"
+	"tg-handler/prompts"
+)
+
+// Image describer through vision model backend
+type Describer struct {
+	chain    *Chain
+	config   *conf.BotConf
+	template string
+	logger   *logging.Logger
+}
+
+// Constructs describer, nil if template is not set
+// or no model of describe chain accepts images
+func NewDescriber(
+	chains *Chains,
+	botConf *conf.BotConf,
+	template string,
+	logger *logging.Logger,
+) *Describer {
+	if template == "" || !chains.Describe.Vision() {
+		return nil
+	}
+
+	return &Describer{
+		chain:    chains.Describe,
+		config:   botConf,
+		template: template,
+		logger:   logger,
+	}
+}
+
+// Describes base64 image with caption shortly in one request,
+// failing over within chain
+func (d *Describer) Describe(
+	ctx context.Context, image, caption string,
+) (string, error) {
+	// Format prompt
+	prompt := prompts.FmtDescribePrompt(d.template, caption)
+	// Form request without persona
+	request := newRequest(
+		prompt, d.config, d.config.Stages.Describe.Options,
+		PriorityReply, denoising.DenoiseStructured,
+	)
+	request.SystemPrompt = ""
+	request.Images = []string{image}
+
+	description, _, err := d.chain.send(ctx, request, d.logger)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep description in one line
+	return strings.Join(strings.Fields(description), " "), nil
+}
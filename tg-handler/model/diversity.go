@@ -0,0 +1,75 @@
+package model
+
+import (
+	"tg-handler/conf"
+	"tg-handler/logging"
+	"tg-handler/similarity"
+)
+
+// Ollama temperature if not set
+const defaultTemperature = 0.8
+
+// Varies request for candidate attempt: temperature goes up
+// the ladder up to ceiling, seed is shifted, style hint is added
+// to role
+func (m *Model) varyRequest(request *Request, attempt int) *Request {
+	var (
+		diversity = &m.Config.Main.Diversity
+		varied    = *request
+		options   = &varied.Options
+	)
+
+	// Climb temperature ladder
+	if diversity.TemperatureStep != 0 {
+		temperature := float32(defaultTemperature)
+		if options.Temperature != nil {
+			temperature = *options.Temperature
+		}
+		temperature = min(
+			temperature+float32(attempt)*diversity.TemperatureStep,
+			max(diversity.MaxTemperature, temperature),
+		)
+		options.Temperature = &temperature
+	}
+
+	// Shift fixed seed
+	shiftSeed(options, attempt)
+
+	// Add style hint
+	if hints := diversity.StyleHints; len(hints) > 0 {
+		varied.SystemPrompt += "\n\n" + hints[attempt%len(hints)]
+	}
+
+	return &varied
+}
+
+// Shifts fixed seed, unset one is random per request already
+func shiftSeed(options *conf.OptionalSettings, shift int) {
+	if options.Seed != nil {
+		seed := *options.Seed + shift
+		options.Seed = &seed
+	}
+}
+
+// Checks if candidate is near-duplicate of kept ones
+func (m *Model) isNearDuplicate(
+	candidate Candidate, kept Candidates, logger *logging.Logger,
+) bool {
+	maxSimilarity := m.Config.Main.Diversity.MaxSimilarity
+	if maxSimilarity == 0 {
+		return false
+	}
+
+	for _, k := range kept {
+		ratio := similarity.Ratio(candidate.Text, k.Text)
+		if ratio >= maxSimilarity {
+			logger.Debug(
+				"near-duplicate candidate dropped",
+				logging.Candidate(candidate.Text),
+				logging.Similarity(ratio),
+			)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"tg-handler/logging"
+)
+
+// Embedding errors
+var (
+	errEmbeddingNum = errors.New("wrong embedding number")
+)
+
+// Embedding request to Ollama
+type EmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// Embedding response from Ollama
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// Embeds texts with the first available model in chain,
+// failing over like generation
+func (c *Chain) embed(
+	ctx context.Context,
+	texts []string,
+	priority Priority,
+	logger *logging.Logger,
+) ([][]float64, error) {
+	var errs []error
+
+	for _, link := range c.available() {
+		linkLog := logger.With(logging.ModelName(link.name))
+
+		// Wait for scheduler slot
+		release, err := c.scheduler.acquire(ctx, c.bot, priority)
+		if err != nil {
+			return nil, err
+		}
+		embeddings, err := sendEmbedRequest(ctx, link, texts)
+		release()
+
+		if err == nil {
+			return embeddings, nil
+		}
+
+		// Stop on parent context done
+		if ctx.Err() != nil {
+			return nil, ErrCtxDone
+		}
+
+		// Put on cooldown, fail over
+		link.setDown(c.cooldown)
+		linkLog.Error("model failed, failing over", logging.Err(err))
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+	}
+
+	return nil, fmt.Errorf(
+		"%w: %w", errChainExhausted, errors.Join(errs...),
+	)
+}
+
+// Sends Ollama embedding request for link
+func sendEmbedRequest(
+	ctx context.Context, link *link, texts []string,
+) ([][]float64, error) {
+	// Encode request body to JSON data
+	jsonData, err := json.Marshal(&EmbedRequest{
+		Model: link.name,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errMarshalFailed, err)
+	}
+
+	// Drop connection if response takes too long
+	reqCtx, cancel := context.WithTimeout(ctx, link.timeout)
+	defer cancel()
+
+	// Make POST request with JSON data
+	req, err := http.NewRequestWithContext(
+		reqCtx, "POST", link.endpoint+embedPath,
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSendFailed, err)
+	}
+	defer resp.Body.Close()
+
+	// Validate status code
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(
+			"%w %d: %s",
+			errInvalidStatus, resp.StatusCode, string(body),
+		)
+	}
+
+	// Decode response body
+	var response EmbedResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDecodeFailed, err)
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, errEmbeddingNum
+	}
+
+	return response.Embeddings, nil
+}
@@ -0,0 +1,278 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Fallback constants
+const (
+	defaultEndpoint = "http://ollama:11434"
+	generatePath    = "/api/generate"
+	embedPath       = "/api/embed"
+	defaultCooldown = 5 * time.Minute
+)
+
+// Fallback errors
+var (
+	errChainExhausted = errors.New("all models in chain failed")
+)
+
+// Model reachable at endpoint
+type link struct {
+	name     string
+	endpoint string // Base URL
+	timeout  time.Duration
+	plain    bool // Backend lacks structured outputs
+	vision   bool // Model accepts images
+
+	mu        sync.Mutex
+	downUntil time.Time // Skipped until cooldown ends
+}
+
+func newLink(model conf.ModelSettings) *link {
+	var (
+		endpoint = model.Endpoint
+		timeout  = time.Duration(model.Timeout)
+	)
+
+	// Use defaults if not set
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	if timeout == 0 {
+		timeout = waitTimeout
+	}
+
+	return &link{
+		name:     model.Name,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		timeout:  timeout,
+		plain:    model.PlainOutput,
+		vision:   model.Vision,
+	}
+}
+
+// Reports if link is cooling down after failure
+func (l *link) isDown(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return now.Before(l.downUntil)
+}
+
+// Puts link on cooldown
+func (l *link) setDown(cooldown time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.downUntil = time.Now().Add(cooldown)
+}
+
+// Ordered chain of models failing over to the next one.
+// Shared by all chats of bot, so cooldowns are bot-wide.
+type Chain struct {
+	links     []*link
+	cooldown  time.Duration
+	bot       string
+	scheduler *Scheduler // Global
+}
+
+// Model chains per stage
+type Chains struct {
+	Response  *Chain // Candidate generation
+	Select    *Chain // Candidate selection
+	Tags      *Chain // Tags reflection
+	Carma     *Chain // Carma reflection
+	Reflect   *Chain // Combined reflection
+	Refine    *Chain // Refinement pass
+	Translate *Chain // LLM translation
+	Describe  *Chain // Image description
+	Embed     *Chain // Repetition embeddings, nil if not set
+}
+
+// Constructs model chains for all stages from bot config,
+// stages without models share bot chain, which falls back
+// to environment model if not configured.
+func MustNewChains(
+	botConf *conf.BotConf,
+	scheduler *Scheduler,
+	botName string,
+	logger *logging.Logger,
+) *Chains {
+	const errMsg = "failed to get env variable"
+
+	var (
+		models   = botConf.Main.Models
+		stages   = &botConf.Stages
+		cooldown = time.Duration(botConf.Main.FallbackCooldown)
+	)
+
+	// Get model name from environment if no chain
+	if len(models) == 0 {
+		name, ok := os.LookupEnv(envModelVar)
+		if !ok {
+			logger.With(logging.EnvVar(envModelVar)).
+				Panic(errMsg, logging.Err(errGetEnvFailed))
+		}
+		models = []conf.ModelSettings{{Name: name}}
+	}
+
+	// Use default cooldown if not set
+	if cooldown == 0 {
+		cooldown = defaultCooldown
+	}
+
+	// Get stage chain or shared bot chain
+	botChain := newChain(models, cooldown, botName, scheduler)
+	stageChain := func(stage *conf.StageSettings) *Chain {
+		if len(stage.Models) == 0 {
+			return botChain
+		}
+		return newChain(stage.Models, cooldown, botName, scheduler)
+	}
+
+	// Get embedding chain if set
+	var embedChain *Chain
+	if embedding := botConf.Main.Repetition.Embedding; embedding != nil {
+		embedChain = newChain(
+			[]conf.ModelSettings{*embedding}, cooldown, botName, scheduler,
+		)
+	}
+
+	return &Chains{
+		Response:  stageChain(&stages.Response),
+		Select:    stageChain(&stages.Select),
+		Tags:      stageChain(&stages.Tags),
+		Carma:     stageChain(&stages.Carma),
+		Reflect:   stageChain(&stages.Reflect),
+		Refine:    stageChain(&stages.Refine),
+		Translate: stageChain(&stages.Translate),
+		Describe:  stageChain(&stages.Describe),
+		Embed:     embedChain,
+	}
+}
+
+// Constructs model chain
+func newChain(
+	models []conf.ModelSettings,
+	cooldown time.Duration,
+	botName string,
+	scheduler *Scheduler,
+) *Chain {
+	// Accumulate links
+	links := make([]*link, 0, len(models))
+	for _, model := range models {
+		links = append(links, newLink(model))
+	}
+
+	return &Chain{
+		links:     links,
+		cooldown:  cooldown,
+		bot:       botName,
+		scheduler: scheduler,
+	}
+}
+
+// Sends request to the first available model in chain,
+// putting failed models on cooldown and failing over to next ones.
+// Returns text and name of model produced it.
+func (c *Chain) send(
+	ctx context.Context,
+	request *Request,
+	logger *logging.Logger,
+) (string, string, error) {
+	var errs []error
+
+	for _, link := range c.available() {
+		linkLog := logger.With(logging.ModelName(link.name))
+
+		// Send request as link model,
+		// leave free text parsing to plain backends,
+		// images to vision ones
+		linkRequest := *request
+		linkRequest.Model = link.name
+		if link.plain {
+			linkRequest.Format = nil
+			if request.plainCleaner != nil {
+				linkRequest.cleaner = request.plainCleaner
+			}
+		}
+		if !link.vision {
+			linkRequest.Images = nil
+		}
+
+		// Wait for scheduler slot
+		release, err := c.scheduler.acquire(
+			ctx, c.bot, request.priority,
+		)
+		if err != nil {
+			return "", "", err
+		}
+		text, err := sendRequest(
+			ctx, link.endpoint+generatePath, link.timeout,
+			&linkRequest, linkLog,
+		)
+		release()
+
+		// Log fallback success, return
+		if err == nil {
+			if link != c.links[0] {
+				linkLog.Info("served by fallback model")
+			}
+			return text, link.name, nil
+		}
+
+		// Stop on parent context done
+		if ctx.Err() != nil {
+			return "", "", ErrCtxDone
+		}
+
+		// Put on cooldown, fail over
+		link.setDown(c.cooldown)
+		linkLog.Error("model failed, failing over", logging.Err(err))
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+	}
+
+	return "", "", fmt.Errorf(
+		"%w: %w", errChainExhausted, errors.Join(errs...),
+	)
+}
+
+// Reports if any model in chain accepts images
+func (c *Chain) Vision() bool {
+	for _, link := range c.links {
+		if link.vision {
+			return true
+		}
+	}
+	return false
+}
+
+// Gets links not cooling down in order,
+// all links if every one of them is cooling down.
+func (c *Chain) available() []*link {
+	var (
+		now   = time.Now()
+		links = make([]*link, 0, len(c.links))
+	)
+
+	for _, link := range c.links {
+		if !link.isDown(now) {
+			links = append(links, link)
+		}
+	}
+
+	if len(links) == 0 {
+		return c.links
+	}
+	return links
+}
@@ -2,9 +2,9 @@ package model
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"tg-handler/carma"
@@ -14,35 +14,23 @@ import (
 	"tg-handler/memory"
 	"tg-handler/names"
 	"tg-handler/prompts"
-	"tg-handler/selectIdx"
+	"tg-handler/reflection"
 	"tg-handler/tags"
 )
 
 // Constants
 const (
-	envModelVar  = "LLM_MODEL"
-	apiUrl       = "http://ollama:11434/api/generate"
-	retryTime    = 10 * time.Second
-	waitTimeout  = 2 * time.Minute
-	maxSelectTry = 5
-	maxTagsTry   = 5
-	maxCarmaTry  = 5
+	envModelVar   = "LLM_MODEL"
+	retryTime     = 10 * time.Second
+	waitTimeout   = 2 * time.Minute
+	maxSelectTry  = 5
+	maxRateTry    = 5
+	maxTagsTry    = 5
+	maxCarmaTry   = 5
+	maxReflectTry = 5
+	maxRefineTry  = 5
 )
 
-// Message abstraction
-type Message interface {
-	SenderProvider
-	LineProvider
-}
-
-type SenderProvider interface {
-	Sender() string
-}
-
-type LineProvider interface {
-	Line() string
-}
-
 // Model errors
 var (
 	errGetEnvFailed = errors.New("failed to get env variable")
@@ -51,16 +39,18 @@ var (
 
 // LLM model
 type Model struct {
-	Name      string
+	Chains    *Chains // Shared by bot
 	Config    *conf.BotConf
 	Prompts   *prompts.Prompts
 	Memory    *memory.Memory
 	Names     *names.Names
 	ChatTitle string
+	Images    []string // Base64 images of new message
 	Logger    *logging.Logger
 }
 
 func New(
+	chains *Chains,
 	botConf *conf.BotConf,
 	prompts *prompts.Prompts,
 	memory *memory.Memory,
@@ -68,17 +58,8 @@ func New(
 	chatTitle string,
 	logger *logging.Logger,
 ) *Model {
-	const errMsg = "failed to get env variable"
-
-	// Get model name
-	name, ok := os.LookupEnv(envModelVar)
-	if !ok {
-		logger.With(logging.EnvVar(envModelVar)).
-			Panic(errMsg, logging.Err(errGetEnvFailed))
-	}
-
 	return &Model{
-		Name:      name,
+		Chains:    chains,
 		Config:    botConf,
 		Prompts:   prompts,
 		Memory:    memory,
@@ -90,7 +71,13 @@ func New(
 
 // Replies to new message as model
 func (m *Model) Reply(ctx context.Context) (string, error) {
-	candidates, err := m.genCandidates(ctx)
+	request, err := m.responseRequest(ctx)
+	if errors.Is(err, ErrCtxDone) {
+		return "", err
+	}
+	request.Images = m.Images
+
+	candidates, err := m.genGuardedCandidates(ctx, request)
 	if errors.Is(err, ErrCtxDone) {
 		return "", err
 	}
@@ -100,61 +87,136 @@ func (m *Model) Reply(ctx context.Context) (string, error) {
 		return "", err
 	}
 
-	return bestCandidate, nil
+	// Refine if enabled
+	text := bestCandidate.Text
+	if m.Config.Main.Refine {
+		text, err = m.refine(ctx, text)
+		if errors.Is(err, ErrCtxDone) {
+			return "", err
+		}
+	}
+
+	// Record model produced reply
+	m.Logger.Info(
+		"reply produced", logging.ModelName(bestCandidate.Model),
+	)
+	return text, nil
 }
 
-// Reflects on response
+// Reflects on replies (one or more lines) in configured mode
 func (m *Model) Reflect(
 	ctx context.Context,
 	user string,
-	reply Message,
+	replies string,
 ) error {
+	// Get carma update and tags
 	var (
-		botContacts = m.Memory.BotContacts
+		carmaUpdate carma.Update
+		tags        tags.Tags
+		err         error
 	)
+	switch m.Config.Main.ReflectionMode {
+	case conf.ReflectionCombined:
+		carmaUpdate, tags, err = m.reflectCombined(ctx, replies)
+	default:
+		carmaUpdate, tags, err = m.reflectSeparate(ctx, replies)
+	}
+	if errors.Is(err, ErrCtxDone) {
+		return err
+	}
+
+	// Update carma and persona, keeping the rest of contact
+	m.Memory.BotContacts.SetReflection(user, carmaUpdate, tags)
 
-	// Get contact to update
-	botContact := botContacts.Get(user)
+	return nil
+}
+
+// Reflects on response in one call,
+// falls back to separate calls on tries exhaustion
+func (m *Model) reflectCombined(
+	ctx context.Context,
+	replyLine string,
+) (carma.Update, tags.Tags, error) {
+	reflection, err := m.genReflection(ctx, replyLine)
+	if errors.Is(err, ErrCtxDone) {
+		return carma.Fallback(), nil, err
+	}
+	if err != nil {
+		m.Logger.Info("falling back to separate reflection")
+		return m.reflectSeparate(ctx, replyLine)
+	}
+
+	return reflection.CarmaUpdate, reflection.Tags, nil
+}
 
+// Reflects on response in two calls
+func (m *Model) reflectSeparate(
+	ctx context.Context,
+	replyLine string,
+) (carma.Update, tags.Tags, error) {
 	// Update carma
-	carmaUpdate, err := m.genCarmaUpdate(ctx, reply.Line())
+	carmaUpdate, err := m.genCarmaUpdate(ctx, replyLine)
 	if errors.Is(err, ErrCtxDone) {
-		return err
+		return carmaUpdate, nil, err
 	}
-	botContact.Carma.Apply(carmaUpdate)
 
 	// Update persona
-	tags, err := m.genTags(ctx, reply.Line())
+	tags, err := m.genTags(ctx, replyLine)
 	if errors.Is(err, ErrCtxDone) {
-		return err
+		return carmaUpdate, nil, err
 	}
-	botContact.Tags = tags
 
-	// Reset contacts
-	botContacts.Set(user, botContact)
+	return carmaUpdate, tags, nil
+}
 
-	return nil
+// Generates candidates guarded against repetition,
+// regenerating within limit if all are repetitive
+func (m *Model) genGuardedCandidates(
+	ctx context.Context,
+	request *Request,
+) (Candidates, error) {
+	maxRegens := m.Config.Main.Repetition.MaxRegens
+
+	for round := 0; ; round++ {
+		candidates, err := m.genCandidates(ctx, request, round)
+		if errors.Is(err, ErrCtxDone) {
+			return nil, err
+		}
+
+		guarded, fresh, err := m.guardRepetition(ctx, candidates)
+		if errors.Is(err, ErrCtxDone) {
+			return nil, err
+		}
+		if fresh || round == maxRegens {
+			return guarded, nil
+		}
+
+		m.Logger.Info("all candidates repetitive, regenerating")
+	}
 }
 
-// Generates candidates
+// Generates varied candidates, dropping near-duplicates
+// and regenerating to refill the pool within refill limit.
+// Round shifts variation to differ from previous rounds.
 func (m *Model) genCandidates(
 	ctx context.Context,
-) ([]string, error) {
+	request *Request,
+	round int,
+) (Candidates, error) {
 	logger := m.Logger
 
 	var (
 		candidateNum = m.Config.Main.CandidateNum
-		candidates   = make([]string, 0, candidateNum)
+		maxAttempts  = candidateNum + m.Config.Main.Diversity.MaxRefills
+		candidates   = make(Candidates, 0, candidateNum)
+		dropped      int
 	)
 
 	// Get start time
 	start := time.Now()
 
-	// Form request
-	request := m.newRequest(m.Prompts.Response)
-
 	// Generate candidates
-	for i := range candidateNum {
+	for i := 0; len(candidates) < candidateNum && i < maxAttempts; i++ {
 		// Get iteration start time
 		iStart := time.Now()
 
@@ -163,9 +225,22 @@ func (m *Model) genCandidates(
 		iterLog.Info("generating candidate")
 
 		// Get new candidate
-		candidate, err := sendRequestEternal(ctx, request, iterLog)
+		text, model, err := sendRequestEternal(
+			ctx, m.Chains.Response,
+			m.varyRequest(request, round*maxAttempts+i), iterLog,
+		)
 		if errors.Is(err, ErrCtxDone) {
-			return []string{}, ErrCtxDone
+			return Candidates{}, ErrCtxDone
+		}
+		candidate := Candidate{
+			Text:  text,
+			Model: model,
+		}
+
+		// Drop near-duplicate
+		if m.isNearDuplicate(candidate, candidates, iterLog) {
+			dropped++
+			continue
 		}
 
 		// Append to candidates
@@ -174,75 +249,20 @@ func (m *Model) genCandidates(
 		// Log successs
 		iterLog.Debug(
 			"candidate generated",
-			logging.Candidate(candidate),
+			logging.Candidate(text),
+			logging.ModelName(model),
 			logging.Duration(time.Since(iStart)),
 		)
 	}
 
 	// Log final success
 	logger.With(
+		logging.Dropped(dropped),
 		logging.Duration(time.Since(start)),
 	).Info("candidates generated")
 	return candidates, nil
 }
 
-// Select the best candidate
-func (m *Model) selectBestCandidate(
-	ctx context.Context,
-	candidates Candidates,
-) (string, error) {
-	logger := m.Logger
-
-	// One candidate to be selected from, return it
-	if len(candidates) == 1 {
-		return candidates[0], nil
-	}
-
-	// Get start time
-	start := time.Now()
-
-	// Format prompt
-	prompt := prompts.FinFmtSelectPrompt(
-		m.Prompts.Select, candidates,
-	)
-	// Form request
-	request := m.newRequest(prompt)
-
-	// Try to select the best candidate
-	for i := range maxSelectTry {
-		// Log start
-		iterLog := logger.With(logging.Iter(i + 1))
-		iterLog.Info("selecting candidate")
-
-		// Try to get select index
-		selectStr, err := sendRequestEternal(ctx, request, iterLog)
-		if errors.Is(err, ErrCtxDone) {
-			return "", err
-		}
-		selectIdx, err := selectIdx.New(selectStr, len(candidates))
-
-		// Log success, return
-		if err == nil {
-			candidateSelected := candidates[selectIdx]
-			iterLog.Info(
-				"candidate selected",
-				logging.Candidate(candidateSelected),
-				logging.Duration(time.Since(start)),
-			)
-			return candidateSelected, nil
-		}
-
-		// Log failure, continue
-		iterLog.Error("selection failed", logging.Err(
-			fmt.Errorf("%w: %v", errGenFailed, err),
-		))
-	}
-
-	// Fall back
-	logger.Info("using fallback value for candidates")
-	return candidates.Fallback(), nil
-}
-
 // Generates unique tags
 func (m *Model) genTags(
 	ctx context.Context,
@@ -256,7 +276,9 @@ func (m *Model) genTags(
 	// Format prompt
 	prompt := prompts.FinFmtTagsPrompt(m.Prompts.Tags, replyLine)
 	// Form request
-	request := m.newRequest(prompt)
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Tags, PriorityReflect, tags.Schema,
+	)
 
 	for i := range maxTagsTry {
 		// Log start
@@ -264,11 +286,13 @@ func (m *Model) genTags(
 		iterLog.Info("generating tags")
 
 		// Get tags
-		rawTags, err := sendRequestEternal(ctx, request, iterLog)
+		rawTags, _, err := sendRequestEternal(
+			ctx, m.Chains.Tags, request, iterLog,
+		)
 		if errors.Is(err, ErrCtxDone) {
 			return nil, err
 		}
-		tags, err := tags.New(rawTags, m.Memory.Limits.Tags, iterLog)
+		tags, err := tags.Parse(rawTags, m.Memory.Limits.Tags, iterLog)
 
 		// Log success, return
 		if err == nil {
@@ -306,7 +330,9 @@ func (m *Model) genCarmaUpdate(
 	// Format prompt
 	prompt := prompts.FinFmtCarmaPrompt(m.Prompts.Carma, replyLine)
 	// Form request
-	request := m.newRequest(prompt)
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Carma, PriorityReflect, carma.Schema,
+	)
 
 	for i := range maxCarmaTry {
 		// Log start
@@ -314,11 +340,13 @@ func (m *Model) genCarmaUpdate(
 		iterLog.Info("generating carma update")
 
 		// Try to get carma update
-		carmaUpdateStr, err := sendRequestEternal(ctx, request, iterLog)
+		carmaUpdateStr, _, err := sendRequestEternal(
+			ctx, m.Chains.Carma, request, iterLog,
+		)
 		if errors.Is(err, ErrCtxDone) {
 			return carma.Fallback(), err
 		}
-		carmaUpdate, err := carma.NewUpdate(carmaUpdateStr)
+		carmaUpdate, err := carma.ParseUpdate(carmaUpdateStr)
 
 		// Log success, return
 		if err == nil {
@@ -344,9 +372,88 @@ func (m *Model) genCarmaUpdate(
 	return carma.Fallback(), nil
 }
 
-// Forms new request using model's model and config
-func (m *Model) newRequest(prompt string) *Request {
-	return newRequest(prompt, m.Name, m.Config, m.getReplyCleaner())
+// Generates combined reflection
+func (m *Model) genReflection(
+	ctx context.Context,
+	replyLine string,
+) (*reflection.Reflection, error) {
+	logger := m.Logger
+
+	// Get start time
+	start := time.Now()
+
+	// Format prompt
+	prompt := prompts.FinFmtReflectPrompt(m.Prompts.Reflect, replyLine)
+	// Form request
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Reflect, PriorityReflect,
+		reflection.Schema,
+	)
+
+	for i := range maxReflectTry {
+		// Log start
+		iterLog := logger.With(logging.Iter(i + 1))
+		iterLog.Info("generating reflection")
+
+		// Try to get reflection
+		reflectionStr, _, err := sendRequestEternal(
+			ctx, m.Chains.Reflect, request, iterLog,
+		)
+		if errors.Is(err, ErrCtxDone) {
+			return nil, err
+		}
+		reflection, err := reflection.Parse(
+			reflectionStr, m.Memory.Limits.Tags, iterLog,
+		)
+
+		// Log success, return
+		if err == nil {
+			iterLog.Info(
+				"reflection generated",
+				logging.CarmaUpdate(reflection.CarmaUpdate.String()),
+				logging.Tags(reflection.Tags.String()),
+				logging.Rationale(reflection.Rationale),
+				logging.Duration(time.Since(start)),
+			)
+			return reflection, nil
+		}
+
+		// Log failure, continue
+		iterLog.Error(
+			"failed to generate reflection",
+			logging.Err(
+				fmt.Errorf("%w: %v", errGenFailed, err),
+			),
+		)
+	}
+
+	return nil, errGenFailed
+}
+
+// Forms new request using model's config and stage options
+func (m *Model) newRequest(
+	prompt string, stage *conf.StageSettings, priority Priority,
+) *Request {
+	return newRequest(
+		prompt, m.Config, stage.Options, priority, m.getReplyCleaner(),
+	)
+}
+
+// Forms new request constrained by JSON schema,
+// free text of plain backends is cleaned as reply
+func (m *Model) newStructuredRequest(
+	prompt string,
+	stage *conf.StageSettings,
+	priority Priority,
+	format json.RawMessage,
+) *Request {
+	request := newRequest(
+		prompt, m.Config, stage.Options, priority,
+		denoising.DenoiseStructured,
+	)
+	request.Format = format
+	request.plainCleaner = m.getReplyCleaner()
+	return request
 }
 
 // Gets reply cleaner
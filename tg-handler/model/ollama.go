@@ -32,23 +32,39 @@ type Request struct {
 	Stream       bool                  `json:"stream"`
 	SystemPrompt string                `json:"system,omitempty"`
 	Options      conf.OptionalSettings `json:"options"`
+	KeepAlive    conf.KeepAlive        `json:"keep_alive,omitempty"`
+	Format       json.RawMessage       `json:"format,omitempty"` // Schema
 	Context      []int                 `json:"context,omitempty"`
+	Images       []string              `json:"images,omitempty"` // Base64
 	cleaner      func(string) string
+	plainCleaner func(string) string // Free text of plain backends
+	priority     Priority
 }
 
+// Constructs request with stage options and priority,
+// model is set by chain on sending
 func newRequest(
 	prompt string,
-	model string,
 	botConf *conf.BotConf,
+	options conf.OptionalSettings,
+	priority Priority,
 	cleaner func(string) string,
 ) *Request {
+	// Move keep alive from options to request level
+	var keepAlive conf.KeepAlive
+	if options.KeepAlive != nil {
+		keepAlive = *options.KeepAlive
+		options.KeepAlive = nil
+	}
+
 	return &Request{
-		Model:        model,
 		Prompt:       prompt,
 		Stream:       false,
 		SystemPrompt: botConf.Main.Role,
-		Options:      botConf.Optional,
+		Options:      options,
+		KeepAlive:    keepAlive,
 		cleaner:      cleaner,
+		priority:     priority,
 	}
 }
 
@@ -67,28 +83,34 @@ type Response struct {
 	EvalDuration       int64  `json:"eval_duration,omitempty"`
 }
 
-// Eternally sends request to API and logs error
+// Eternally sends request to model chain and logs error,
+// returns text and name of model produced it
 func sendRequestEternal(
 	ctx context.Context,
+	chain *Chain,
 	request *Request,
 	logger *logging.Logger,
-) (string, error) {
+) (string, string, error) {
 	var (
-		text string
-		err  error
+		text  string
+		model string
+		err   error
 	)
 
 	// Get text
 	for {
 		// Check if parent context (shutdown is done before trying)
 		if ctx.Err() != nil {
-			return "", ErrCtxDone
+			return "", "", ErrCtxDone
 		}
 
-		text, err = sendRequest(ctx, request, logger)
+		text, model, err = chain.send(ctx, request, logger)
 		if err == nil {
 			break
 		}
+		if errors.Is(err, ErrCtxDone) {
+			return "", "", err
+		}
 
 		logger.Error("request failed retrying", logging.Err(err))
 
@@ -96,16 +118,18 @@ func sendRequestEternal(
 		case <-time.After(retryTime):
 			continue
 		case <-ctx.Done():
-			return "", ErrCtxDone
+			return "", "", ErrCtxDone
 		}
 	}
 
-	return text, nil
+	return text, model, nil
 }
 
-// Sends Ollama request
+// Sends Ollama request to URL with timeout
 func sendRequest(
 	ctx context.Context,
+	url string,
+	timeout time.Duration,
 	request *Request,
 	logger *logging.Logger,
 ) (string, error) {
@@ -117,12 +141,12 @@ func sendRequest(
 
 	// Create context with timeout for this request
 	// to drop connection if response takes too long
-	reqCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Make POST request with JSON data
 	req, err := http.NewRequestWithContext(
-		reqCtx, "POST", apiUrl, bytes.NewBuffer(jsonData),
+		reqCtx, "POST", url, bytes.NewBuffer(jsonData),
 	)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
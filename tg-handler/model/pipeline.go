@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tg-handler/denoising"
+	"tg-handler/logging"
+	"tg-handler/prompts"
+)
+
+// Forms response request, running pipeline steps before
+// the last one if configured. Intermediate outputs are only logged.
+func (m *Model) responseRequest(ctx context.Context) (*Request, error) {
+	// No pipeline, use response template
+	pipeline := m.Config.Pipeline
+	if len(pipeline) == 0 {
+		return m.newRequest(
+			m.Prompts.Response, &m.Config.Stages.Response, PriorityReply,
+		), nil
+	}
+
+	// Run steps before the last one
+	vars := m.Prompts.StepVars()
+	last := len(pipeline) - 1
+	for i := range pipeline[:last] {
+		err := m.runStep(ctx, i, vars)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Form the last step request to generate candidates
+	step := &pipeline[last]
+	prompt, err := prompts.FmtStepPrompt(step, vars)
+	if err != nil {
+		m.logStepFailure(step.Output, err)
+		return m.newRequest(
+			m.Prompts.Response, &m.Config.Stages.Response, PriorityReply,
+		), nil
+	}
+	return newRequest(
+		prompt, m.Config, step.Options, PriorityReply, m.getReplyCleaner(),
+	), nil
+}
+
+// Runs pipeline step setting its output variable,
+// an empty one if prompt formatting failed
+func (m *Model) runStep(
+	ctx context.Context, i int, vars map[string]string,
+) error {
+	step := &m.Config.Pipeline[i]
+	stepLog := m.Logger.With(logging.Step(step.Output))
+
+	// Get start time
+	start := time.Now()
+
+	// Format prompt
+	vars[step.Output] = ""
+	prompt, err := prompts.FmtStepPrompt(step, vars)
+	if err != nil {
+		m.logStepFailure(step.Output, err)
+		return nil
+	}
+
+	// Log start
+	stepLog.Info("running pipeline step")
+
+	// Generate step output
+	request := newRequest(
+		prompt, m.Config, step.Options, PriorityReply,
+		denoising.DenoiseStructured,
+	)
+	request.Images = m.Images
+	output, model, err := sendRequestEternal(
+		ctx, m.Chains.Response, request, stepLog,
+	)
+	if err != nil {
+		return err
+	}
+	vars[step.Output] = output
+
+	// Log success
+	stepLog.Debug(
+		"pipeline step done",
+		logging.StepOutput(output),
+		logging.ModelName(model),
+		logging.Duration(time.Since(start)),
+	)
+	return nil
+}
+
+// Logs step prompt formatting failure
+func (m *Model) logStepFailure(output string, err error) {
+	m.Logger.Error(
+		"formatting step prompt failed",
+		logging.Step(output),
+		logging.Err(fmt.Errorf("%w: %v", errGenFailed, err)),
+	)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tg-handler/critique"
+	"tg-handler/logging"
+	"tg-handler/prompts"
+)
+
+// Critiques reply against persona and memory, rewriting it once
+// if needed; keeps reply on tries exhaustion
+func (m *Model) refine(ctx context.Context, reply string) (string, error) {
+	logger := m.Logger
+
+	// Get start time
+	start := time.Now()
+
+	// Format prompt
+	prompt := prompts.FinFmtRefinePrompt(m.Prompts.Refine, reply)
+	// Form request
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Refine, PriorityReply, critique.Schema,
+	)
+
+	for i := range maxRefineTry {
+		// Log start
+		iterLog := logger.With(logging.Iter(i + 1))
+		iterLog.Info("refining reply")
+
+		// Try to get critique
+		critiqueStr, _, err := sendRequestEternal(
+			ctx, m.Chains.Refine, request, iterLog,
+		)
+		if errors.Is(err, ErrCtxDone) {
+			return reply, err
+		}
+		critique, err := critique.Parse(critiqueStr)
+
+		// Log failure, continue
+		if err != nil {
+			iterLog.Error("failed to refine reply", logging.Err(
+				fmt.Errorf("%w: %v", errGenFailed, err),
+			))
+			continue
+		}
+
+		// Keep reply
+		if !critique.Rewrite {
+			iterLog.Debug(
+				"reply kept",
+				logging.Critique(critique.Critique),
+				logging.Duration(time.Since(start)),
+			)
+			return reply, nil
+		}
+
+		// Rewrite reply unless cleaned out
+		refined := m.getReplyCleaner()(critique.Text)
+		if refined == "" {
+			iterLog.Error("failed to refine reply", logging.Err(
+				fmt.Errorf("%w: %v", errGenFailed, "empty rewrite"),
+			))
+			continue
+		}
+		iterLog.Debug(
+			"reply rewritten",
+			logging.Critique(critique.Critique),
+			logging.Before(reply),
+			logging.After(refined),
+			logging.Duration(time.Since(start)),
+		)
+		return refined, nil
+	}
+
+	// Fall back
+	logger.Info("using unrefined reply")
+	return reply, nil
+}
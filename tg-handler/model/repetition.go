@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+	"tg-handler/similarity"
+)
+
+// Guards candidates against repeating recent bot lines:
+// removes or penalizes repetitive ones, keeps all penalized
+// and reports false if none is fresh
+func (m *Model) guardRepetition(
+	ctx context.Context, candidates Candidates,
+) (Candidates, bool, error) {
+	repetition := &m.Config.Main.Repetition
+	if repetition.Lines == 0 || len(candidates) == 0 {
+		return candidates, true, nil
+	}
+
+	// Get recent bot lines
+	lines := m.recentBotLines(repetition.Lines)
+	if len(lines) == 0 {
+		return candidates, true, nil
+	}
+
+	// Get repetition of candidates
+	scores, err := m.repetitionScores(ctx, candidates, lines)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Penalize or remove repetitive ones
+	guarded := make(Candidates, 0, len(candidates))
+	penalized := make(Candidates, 0, len(candidates))
+	for i, candidate := range candidates {
+		candidate.Repetition = scores[i]
+		penalized = append(penalized, candidate)
+
+		if scores[i] > 0 {
+			m.Logger.Debug(
+				"repetitive candidate",
+				logging.Candidate(candidate.Text),
+				logging.Similarity(scores[i]),
+			)
+			if repetition.Action == conf.RepetitionRemove {
+				continue
+			}
+		}
+		guarded = append(guarded, candidate)
+	}
+
+	// Report no fresh candidates
+	if !slices.ContainsFunc(penalized, func(c Candidate) bool {
+		return c.Repetition == 0
+	}) {
+		return penalized, false, nil
+	}
+	return guarded, true, nil
+}
+
+// Gets repetition of candidates: the highest of n-gram overlap and
+// embedding similarity exceeding their limits, 0 if none exceeds
+func (m *Model) repetitionScores(
+	ctx context.Context, candidates Candidates, lines []string,
+) ([]float64, error) {
+	repetition := &m.Config.Main.Repetition
+	scores := make([]float64, len(candidates))
+
+	// Check n-gram overlap
+	for i, candidate := range candidates {
+		overlap := similarity.NGramOverlap(
+			candidate.Text, lines, repetition.NGram,
+		)
+		if overlap > repetition.MaxOverlap {
+			scores[i] = overlap
+		}
+	}
+
+	// Check embedding similarity if set
+	if m.Chains.Embed == nil {
+		return scores, nil
+	}
+	embeddings, err := m.Chains.Embed.embed(
+		ctx,
+		append(candidates.Texts(), lines...),
+		PriorityReply,
+		m.Logger,
+	)
+	if errors.Is(err, ErrCtxDone) {
+		return nil, err
+	}
+	if err != nil {
+		m.Logger.Error("embedding skipped", logging.Err(err))
+		return scores, nil
+	}
+
+	lineEmbeddings := embeddings[len(candidates):]
+	for i := range candidates {
+		for _, lineEmbedding := range lineEmbeddings {
+			cosine := similarity.Cosine(embeddings[i], lineEmbedding)
+			if cosine > repetition.MaxEmbedding {
+				scores[i] = max(scores[i], cosine)
+			}
+		}
+	}
+	return scores, nil
+}
+
+// Gets up to limit recent unique bot lines from reply chain
+// and chat queue without sender prefix
+func (m *Model) recentBotLines(lim int) []string {
+	return botLines(
+		m.Memory.ReplyChainLines, m.Memory.ChatQueueLines,
+		m.Names.BotSender+": ", lim,
+	)
+}
+
+// Gets up to limit last unique lines with prefix in time order.
+// Both sources are in time order, chain lines missing in queue
+// left its window, so they precede all queue lines.
+func botLines(chain, queue []string, prefix string, lim int) []string {
+	var lines []string
+
+	// Merge sources by time
+	merged := make([]string, 0, len(chain)+len(queue))
+	for _, line := range chain {
+		if !slices.Contains(queue, line) {
+			merged = append(merged, line)
+		}
+	}
+	merged = append(merged, queue...)
+
+	// Walk back from the latest line
+	for i := len(merged) - 1; i >= 0 && len(lines) < lim; i-- {
+		line := merged[i]
+		if len(line) < len(prefix) ||
+			!strings.EqualFold(line[:len(prefix)], prefix) {
+			continue
+		}
+		text := line[len(prefix):]
+		if !slices.Contains(lines, text) {
+			lines = append(lines, text)
+		}
+	}
+
+	// Restore time order
+	slices.Reverse(lines)
+	return lines
+}
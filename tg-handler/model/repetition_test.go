@@ -0,0 +1,72 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBotLines(t *testing.T) {
+	const prefix = "Bot: "
+
+	tests := []struct {
+		name  string
+		chain []string
+		queue []string
+		lim   int
+		want  []string
+	}{
+		{
+			name: "empty",
+			lim:  3,
+			want: nil,
+		},
+		{
+			name:  "queue only",
+			queue: []string{"Bot: a", "User: x", "Bot: b"},
+			lim:   3,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "chain only",
+			chain: []string{"Bot: a", "User: x", "Bot: b"},
+			lim:   3,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "chain lines out of queue precede it",
+			chain: []string{"Bot: old", "User: x", "Bot: mid"},
+			queue: []string{"Bot: mid", "User: y", "Bot: new"},
+			lim:   3,
+			want:  []string{"old", "mid", "new"},
+		},
+		{
+			name:  "limit keeps the latest",
+			chain: []string{"Bot: old", "User: x"},
+			queue: []string{"Bot: a", "Bot: b", "Bot: c"},
+			lim:   2,
+			want:  []string{"b", "c"},
+		},
+		{
+			name:  "repeated line counted once at its latest",
+			chain: []string{"Bot: a", "User: x"},
+			queue: []string{"Bot: b", "Bot: a"},
+			lim:   3,
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "prefix case insensitive",
+			queue: []string{"bot: a", "Botany: b"},
+			lim:   3,
+			want:  []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := botLines(tt.chain, tt.queue, prefix, tt.lim)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("botLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
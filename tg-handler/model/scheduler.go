@@ -0,0 +1,258 @@
+package model
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Scheduler defaults
+const (
+	defaultMaxInFlight     = 1
+	defaultMetricsInterval = time.Minute
+)
+
+// Request priority, higher served first
+type Priority int
+
+const (
+	PriorityReflect Priority = iota // Background reflection
+	PriorityReply                   // User-facing reply
+	priorityNum
+)
+
+var priorityNames = [priorityNum]string{
+	PriorityReflect: "reflect",
+	PriorityReply:   "reply",
+}
+
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+// Waiting request of bot
+type ticket struct {
+	bot     string
+	ready   chan struct{} // Closed when granted
+	granted bool
+}
+
+// Queued tickets of one priority, round-robin over bots
+type level struct {
+	queues map[string][]*ticket // FIFO per bot
+	bots   []string             // Bots with queued tickets
+	next   int                  // Bot to be served next
+}
+
+func newLevel() *level {
+	return &level{
+		queues: make(map[string][]*ticket),
+	}
+}
+
+// Global LLM request scheduler shared by all bots and stages.
+// Limits requests in flight, serves higher priority first,
+// and alternates between bots within priority.
+type Scheduler struct {
+	mu          sync.Mutex
+	maxInFlight int
+	inFlight    int
+	levels      [priorityNum]*level
+	logger      *logging.Logger
+}
+
+func NewScheduler(
+	settings *conf.SchedulerSettings, logger *logging.Logger,
+) *Scheduler {
+	maxInFlight := settings.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+
+	s := &Scheduler{
+		maxInFlight: maxInFlight,
+		logger:      logger,
+	}
+	for p := range s.levels {
+		s.levels[p] = newLevel()
+	}
+	return s
+}
+
+// Scheduler state snapshot
+type Stats struct {
+	InFlight int
+	Queued   [priorityNum]int
+}
+
+// Gets scheduler state snapshot
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{InFlight: s.inFlight}
+	for p, l := range s.levels {
+		for _, queue := range l.queues {
+			stats.Queued[p] += len(queue)
+		}
+	}
+	return stats
+}
+
+// Logs queue depth with interval until context done
+func (s *Scheduler) Monitor(
+	ctx context.Context, interval time.Duration,
+) {
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	defer s.logger.Info("scheduler monitor shut down gracefully")
+	for {
+		select {
+		case <-t.C:
+			stats := s.Stats()
+			s.logger.Info(
+				"scheduler stats",
+				logging.InFlight(stats.InFlight),
+				logging.QueuedReply(stats.Queued[PriorityReply]),
+				logging.QueuedReflect(stats.Queued[PriorityReflect]),
+			)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Waits for free slot, returns its release function.
+// Leaves queue when context done.
+func (s *Scheduler) acquire(
+	ctx context.Context, bot string, p Priority,
+) (func(), error) {
+	t := &ticket{
+		bot:   bot,
+		ready: make(chan struct{}),
+	}
+
+	// Queue ticket and serve
+	s.mu.Lock()
+	s.enqueue(t, p)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// Give slot back if granted meanwhile
+		if t.granted {
+			s.inFlight--
+			s.dispatch()
+		} else {
+			s.remove(t, p)
+		}
+		return nil, ErrCtxDone
+	}
+}
+
+// Frees slot and serves next ticket
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	s.dispatch()
+}
+
+// Queues ticket (lock held)
+func (s *Scheduler) enqueue(t *ticket, p Priority) {
+	l := s.levels[p]
+	if len(l.queues[t.bot]) == 0 {
+		l.bots = append(l.bots, t.bot)
+	}
+	l.queues[t.bot] = append(l.queues[t.bot], t)
+
+	s.logger.Debug(
+		"request queued",
+		logging.BotName(t.bot),
+		logging.Priority(p.String()),
+		logging.QueueLen(len(l.queues[t.bot])),
+	)
+}
+
+// Removes ticket from queue (lock held)
+func (s *Scheduler) remove(t *ticket, p Priority) {
+	l := s.levels[p]
+	queue := l.queues[t.bot]
+	for i, queued := range queue {
+		if queued == t {
+			l.queues[t.bot] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(l.queues[t.bot]) == 0 {
+		l.dropBot(t.bot)
+	}
+}
+
+// Grants slots while available (lock held)
+func (s *Scheduler) dispatch() {
+	for s.inFlight < s.maxInFlight {
+		t := s.pop()
+		if t == nil {
+			return
+		}
+		s.inFlight++
+		t.granted = true
+		close(t.ready)
+	}
+}
+
+// Pops ticket of highest priority, next bot in turn (lock held)
+func (s *Scheduler) pop() *ticket {
+	for p := priorityNum - 1; p >= 0; p-- {
+		l := s.levels[p]
+		if len(l.bots) == 0 {
+			continue
+		}
+
+		// Take bot in turn
+		l.next %= len(l.bots)
+		bot := l.bots[l.next]
+		queue := l.queues[bot]
+		t := queue[0]
+		l.queues[bot] = queue[1:]
+
+		// Pass turn to next bot
+		if len(l.queues[bot]) == 0 {
+			l.dropBot(bot)
+		} else {
+			l.next++
+		}
+		return t
+	}
+	return nil
+}
+
+// Drops bot without queued tickets from turns
+func (l *level) dropBot(bot string) {
+	for i, b := range l.bots {
+		if b == bot {
+			l.bots = append(l.bots[:i], l.bots[i+1:]...)
+			if i < l.next {
+				l.next--
+			}
+			break
+		}
+	}
+	delete(l.queues, bot)
+}
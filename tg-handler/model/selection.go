@@ -0,0 +1,385 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+	"tg-handler/prompts"
+	"tg-handler/rating"
+	"tg-handler/selectIdx"
+)
+
+// Select the best candidate in configured mode
+func (m *Model) selectBestCandidate(
+	ctx context.Context,
+	candidates Candidates,
+) (Candidate, error) {
+	logger := m.Logger
+
+	// One candidate to be selected from, return it
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	// Get start time
+	start := time.Now()
+
+	// Get the best candidate index
+	var (
+		bestIdx int
+		err     error
+	)
+	switch m.Config.Main.Selection.Mode {
+	case conf.SelectionTournament:
+		bestIdx, err = m.runTournament(ctx, candidates)
+	case conf.SelectionRubric:
+		bestIdx, err = m.scoreByRubric(ctx, candidates)
+	default:
+		bestIdx, err = m.vote(ctx, candidates, logger)
+	}
+	if errors.Is(err, ErrCtxDone) {
+		return Candidate{}, err
+	}
+
+	// Log success
+	candidateSelected := candidates[bestIdx]
+	logger.Info(
+		"candidate selected",
+		logging.Candidate(candidateSelected.Text),
+		logging.Duration(time.Since(start)),
+	)
+	return candidateSelected, nil
+}
+
+// Gets the best candidate index by judge votes over shuffled orders,
+// falls back to the first one if no votes
+func (m *Model) vote(
+	ctx context.Context,
+	candidates Candidates,
+	logger *logging.Logger,
+) (int, error) {
+	var (
+		selection = &m.Config.Main.Selection
+		scores    = make([]float64, len(candidates))
+		votes     int
+	)
+
+	for v := range selection.Votes {
+		voteLog := logger.With(logging.Vote(v + 1))
+
+		// Keep order for the first vote, shuffle for the rest
+		order := identityOrder(len(candidates))
+		if v > 0 {
+			order = rand.Perm(len(candidates))
+		}
+		shown := make(Candidates, 0, len(candidates))
+		for _, idx := range order {
+			shown = append(shown, candidates[idx])
+		}
+
+		// Get ranking of shown candidates
+		ranking, err := m.judge(
+			ctx, shown, selection.Aggregation, voteLog,
+		)
+		if errors.Is(err, ErrCtxDone) {
+			return 0, err
+		}
+		if err != nil {
+			voteLog.Error("vote skipped", logging.Err(err))
+			continue
+		}
+
+		// Map ranking back to original order and score
+		for pos, shownIdx := range ranking {
+			scores[order[shownIdx]] += float64(points(
+				selection.Aggregation, pos, len(candidates),
+			))
+		}
+		votes++
+	}
+
+	// Fall back if no votes
+	if votes == 0 {
+		logger.Info("using fallback value for candidates")
+		return 0, nil
+	}
+
+	// Penalize repetition by up to all points given
+	maxPoints := points(selection.Aggregation, 0, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] -= candidate.Repetition * float64(votes*maxPoints)
+	}
+
+	// Get the best scored candidate, the earliest on tie
+	bestIdx := 0
+	for i, score := range scores {
+		if score > scores[bestIdx] {
+			bestIdx = i
+		}
+	}
+
+	// Log vote distribution
+	logger.Info(
+		"votes counted",
+		logging.VoteDistribution(fmtScores(scores)),
+	)
+	return bestIdx, nil
+}
+
+// Gets the best candidate index by knockout of pairwise votes,
+// odd candidate out passes to the next round
+func (m *Model) runTournament(
+	ctx context.Context,
+	candidates Candidates,
+) (int, error) {
+	// Start with all candidates in random order
+	alive := rand.Perm(len(candidates))
+
+	for round := 1; len(alive) > 1; round++ {
+		roundLog := m.Logger.With(logging.Round(round))
+		winners := make([]int, 0, (len(alive)+1)/2)
+
+		for i := 0; i < len(alive); i += 2 {
+			// Pass odd one out
+			if i+1 == len(alive) {
+				winners = append(winners, alive[i])
+				continue
+			}
+
+			// Vote on pair
+			pair := Candidates{candidates[alive[i]], candidates[alive[i+1]]}
+			winner, err := m.vote(ctx, pair, roundLog)
+			if err != nil {
+				return 0, err
+			}
+			winners = append(winners, alive[i+winner])
+		}
+
+		alive = winners
+	}
+
+	return alive[0], nil
+}
+
+// Gets the best candidate index by averaged rubric scores,
+// falls back to the first one if no scores
+func (m *Model) scoreByRubric(
+	ctx context.Context,
+	candidates Candidates,
+) (int, error) {
+	var (
+		batchSize = m.Config.Main.Selection.RateBatchSize
+		averages  = make([]float64, len(candidates))
+		bestIdx   = -1
+	)
+
+	for i, candidate := range candidates {
+		candidateLog := m.Logger.With(logging.CandidateNum(i + 1))
+
+		// Accumulate ratings
+		var sum, num int
+		for item := range batchSize {
+			rating, err := m.rate(ctx, candidate, item, candidateLog)
+			if errors.Is(err, ErrCtxDone) {
+				return 0, err
+			}
+			if err != nil {
+				candidateLog.Error("rating skipped", logging.Err(err))
+				continue
+			}
+			sum += int(rating)
+			num++
+		}
+
+		// Skip unrated candidate
+		if num == 0 {
+			continue
+		}
+
+		// Penalize repetition by up to rating range
+		averages[i] = float64(sum)/float64(num) -
+			candidate.Repetition*(rating.Max-rating.Min)
+
+		// Keep the best average, the earliest on tie
+		if bestIdx == -1 || averages[i] > averages[bestIdx] {
+			bestIdx = i
+		}
+	}
+
+	// Fall back if no ratings
+	if bestIdx == -1 {
+		m.Logger.Info("using fallback value for candidates")
+		return 0, nil
+	}
+
+	// Log score distribution
+	m.Logger.Info(
+		"ratings averaged",
+		logging.VoteDistribution(fmtScores(averages)),
+	)
+	return bestIdx, nil
+}
+
+// Rates candidate by rubric
+func (m *Model) rate(
+	ctx context.Context,
+	candidate Candidate,
+	item int,
+	logger *logging.Logger,
+) (rating.Rating, error) {
+	// Format prompt
+	prompt := prompts.FinFmtRatePrompt(m.Prompts.Rate, candidate.Text)
+	// Form request, batch items differ in seed
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Select, PriorityReply, rating.Schema,
+	)
+	shiftSeed(&request.Options, item)
+
+	// Try to get rating
+	for i := range maxRateTry {
+		// Log start
+		iterLog := logger.With(logging.Iter(i + 1))
+		iterLog.Info("rating candidate")
+
+		// Try to get rating string
+		ratingStr, _, err := sendRequestEternal(
+			ctx, m.Chains.Select, request, iterLog,
+		)
+		if errors.Is(err, ErrCtxDone) {
+			return 0, err
+		}
+		rating, err := rating.Parse(ratingStr)
+
+		// Log success, return
+		if err == nil {
+			iterLog.Debug("candidate rated", logging.Rating(int(rating)))
+			return rating, nil
+		}
+
+		// Log failure, continue
+		iterLog.Error("rating failed", logging.Err(
+			fmt.Errorf("%w: %v", errGenFailed, err),
+		))
+	}
+
+	return 0, errGenFailed
+}
+
+// Judges shown candidates returning their indices best first:
+// only the best one for majority, full ranking for Borda count
+func (m *Model) judge(
+	ctx context.Context,
+	shown Candidates,
+	aggregation string,
+	logger *logging.Logger,
+) ([]selectIdx.SelectIdx, error) {
+	// Get template and schema for aggregation
+	var (
+		template = m.Prompts.Select
+		format   = selectIdx.SchemaFor(len(shown))
+	)
+	if aggregation == conf.AggregationBorda {
+		template = m.Prompts.Rank
+		format = selectIdx.RankingSchemaFor(len(shown))
+	}
+
+	// Format prompt
+	prompt := prompts.FinFmtSelectPrompt(
+		template, shown.String(), len(shown),
+	)
+
+	// Form request
+	request := m.newStructuredRequest(
+		prompt, &m.Config.Stages.Select, PriorityReply, format,
+	)
+
+	// Try to get ranking
+	for i := range maxSelectTry {
+		// Log start
+		iterLog := logger.With(logging.Iter(i + 1))
+		iterLog.Info("selecting candidate")
+
+		// Try to get select string
+		selectStr, _, err := sendRequestEternal(
+			ctx, m.Chains.Select, request, iterLog,
+		)
+		if errors.Is(err, ErrCtxDone) {
+			return nil, err
+		}
+		ranking, err := parseRanking(
+			selectStr, len(shown), aggregation,
+		)
+
+		// Log success, return
+		if err == nil {
+			iterLog.Debug(
+				"candidates ranked", logging.Ranking(fmtRanking(ranking)),
+			)
+			return ranking, nil
+		}
+
+		// Log failure, continue
+		iterLog.Error("selection failed", logging.Err(
+			fmt.Errorf("%w: %v", errGenFailed, err),
+		))
+	}
+
+	return nil, errGenFailed
+}
+
+// Parses judge output for aggregation
+func parseRanking(
+	s string, lim int, aggregation string,
+) ([]selectIdx.SelectIdx, error) {
+	if aggregation == conf.AggregationBorda {
+		return selectIdx.ParseRanking(s, lim)
+	}
+
+	idx, err := selectIdx.Parse(s, lim)
+	if err != nil {
+		return nil, err
+	}
+	return []selectIdx.SelectIdx{idx}, nil
+}
+
+// Gets points for ranking position
+func points(aggregation string, pos int, num int) int {
+	if aggregation == conf.AggregationBorda {
+		return num - 1 - pos
+	}
+	return 1
+}
+
+// Gets indices in original order
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// Formats scores as "candidate:score" pairs
+func fmtScores(scores []float64) string {
+	pairs := make([]string, 0, len(scores))
+	for i, score := range scores {
+		pairs = append(pairs, fmt.Sprintf("%d:%.1f", i+1, score))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Formats ranking as shown candidate numbers
+func fmtRanking(ranking []selectIdx.SelectIdx) string {
+	nums := make([]string, 0, len(ranking))
+	for _, idx := range ranking {
+		nums = append(nums, strconv.Itoa(int(idx)+1))
+	}
+	return strings.Join(nums, " ")
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+
+	"tg-handler/conf"
+	"tg-handler/denoising"
+	"tg-handler/logging"
+	"tg-handler/prompts"
+)
+
+// Translator through model backend
+type Translator struct {
+	chain    *Chain
+	config   *conf.BotConf
+	template string
+	logger   *logging.Logger
+}
+
+func NewTranslator(
+	chains *Chains,
+	botConf *conf.BotConf,
+	template string,
+	logger *logging.Logger,
+) *Translator {
+	return &Translator{
+		chain:    chains.Translate,
+		config:   botConf,
+		template: template,
+		logger:   logger,
+	}
+}
+
+// Translates text with one request, failing over within chain
+func (t *Translator) Translate(
+	ctx context.Context, text, from, to string,
+) (string, error) {
+	// Format prompt
+	prompt := prompts.FmtTranslatePrompt(t.template, from, to, text)
+	// Form request without persona
+	request := newRequest(
+		prompt, t.config, t.config.Stages.Translate.Options,
+		PriorityReply, denoising.DenoiseStructured,
+	)
+	request.SystemPrompt = ""
+
+	translated, _, err := t.chain.send(ctx, request, t.logger)
+	return translated, err
+}
@@ -1,13 +1,15 @@
 package names
 
 type Names struct {
-	Bot  string
-	User string
+	Bot       string
+	BotSender string // Bot as sender of history lines
+	User      string
 }
 
-func New(bot string, user string) *Names {
+func New(bot string, botSender string, user string) *Names {
 	return &Names{
-		Bot:  bot,
-		User: user,
+		Bot:       bot,
+		BotSender: botSender,
+		User:      user,
 	}
 }
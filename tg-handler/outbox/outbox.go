@@ -0,0 +1,217 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Outbox defaults
+const (
+	defaultInterval    = time.Minute
+	defaultMaxAttempts = 5
+)
+
+// Outbox errors
+var (
+	errReadFailed      = errors.New("failed to read outbox")
+	errUnmarshalFailed = errors.New("failed to unmarshal outbox")
+	errMarshalFailed   = errors.New("failed to marshal outbox")
+	errWriteFailed     = errors.New("failed to write outbox")
+)
+
+// Generated reply waiting for delivery,
+// persisted to be delivered after failures and restarts
+type Entry struct {
+	ID        int64  `json:"id"`
+	ChatID    int64  `json:"chat_id"`
+	ThreadID  int    `json:"thread_id,omitempty"` // Forum topic
+	ChatTitle string `json:"chat_title"`
+	ReplyToID int    `json:"reply_to_id"` // Replied message
+	User      string `json:"user"`
+	UserLine  string `json:"user_line"` // Quoted if replied message deleted
+	Text      string `json:"text"`
+	Format    string `json:"format"`
+	Attempts  int    `json:"attempts"` // Failed deliveries
+
+	// Unsent parts of partially delivered text
+	Rest []string `json:"rest,omitempty"`
+
+	busy bool // Being delivered
+}
+
+// Persistent outbox of bot replies
+type Outbox struct {
+	mu          sync.Mutex
+	path        string
+	entries     []*Entry
+	nextID      int64
+	interval    time.Duration
+	maxAttempts int
+	logger      *logging.Logger
+}
+
+// Loads outbox from path, empty if file does not exist
+func Load(
+	path string, settings *conf.SendingSettings, logger *logging.Logger,
+) *Outbox {
+	const errMsg = "failed to load outbox"
+	logger = logger.With(logging.Path(path))
+
+	o := &Outbox{
+		path:        path,
+		nextID:      1,
+		interval:    time.Duration(settings.OutboxInterval),
+		maxAttempts: settings.OutboxAttempts,
+		logger:      logger,
+	}
+
+	// Use defaults if not set
+	if o.interval <= 0 {
+		o.interval = defaultInterval
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = defaultMaxAttempts
+	}
+
+	// Try to read file
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o
+	} else if err != nil {
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errReadFailed, err),
+		))
+		return o
+	}
+
+	// Unmarshal
+	if err := json.Unmarshal(data, &o.entries); err != nil {
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errUnmarshalFailed, err),
+		))
+		return o
+	}
+
+	// Continue IDs
+	for _, e := range o.entries {
+		o.nextID = max(o.nextID, e.ID+1)
+	}
+
+	logger.Info("outbox loaded", logging.QueueLen(len(o.entries)))
+	return o
+}
+
+// Gets interval between delivery retries
+func (o *Outbox) Interval() time.Duration {
+	return o.interval
+}
+
+// Adds entry taken for delivery, saving it right away
+func (o *Outbox) Add(e *Entry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e.ID = o.nextID
+	e.busy = true
+	o.nextID++
+
+	o.entries = append(o.entries, e)
+	o.save()
+}
+
+// Takes entries not being delivered
+func (o *Outbox) Take() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var taken []*Entry
+	for _, e := range o.entries {
+		if !e.busy {
+			e.busy = true
+			taken = append(taken, e)
+		}
+	}
+	return taken
+}
+
+// Removes delivered or undeliverable entry
+func (o *Outbox) Done(e *Entry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.remove(e)
+	o.save()
+}
+
+// Keeps unsent parts of partially delivered entry for retry
+func (o *Outbox) Keep(e *Entry, rest []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e.Rest = rest
+	o.save()
+}
+
+// Returns entry after failed delivery to be retried,
+// removes it if attempts run out and reports so.
+// Gets failed attempts.
+func (o *Outbox) Fail(e *Entry) (int, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e.Attempts++
+	e.busy = false
+	dropped := e.Attempts >= o.maxAttempts
+	if dropped {
+		o.remove(e)
+	}
+	o.save()
+	return e.Attempts, dropped
+}
+
+// Removes entry (lock held)
+func (o *Outbox) remove(e *Entry) {
+	for i, entry := range o.entries {
+		if entry.ID == e.ID {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Saves entries (lock held)
+func (o *Outbox) save() {
+	const errMsg = "failed to save outbox"
+
+	// Marshal
+	data, err := json.Marshal(o.entries)
+	if err != nil {
+		o.logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errMarshalFailed, err),
+		))
+		return
+	}
+
+	// Write atomically via temporary file
+	tmpPath := o.path + ".tmp"
+	err = os.MkdirAll(filepath.Dir(o.path), 0755)
+	if err == nil {
+		err = os.WriteFile(tmpPath, data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, o.path)
+	}
+	if err != nil {
+		o.logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errWriteFailed, err),
+		))
+	}
+}
@@ -0,0 +1,298 @@
+package outgoing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Sender defaults
+const (
+	defaultChatInterval   = time.Second
+	defaultGlobalInterval = 35 * time.Millisecond
+	defaultMaxRetries     = 3
+	defaultBackoff        = time.Second
+	defaultMaxWait        = time.Minute
+)
+
+// Permanent errors
+var (
+	ErrBotKicked    = errors.New("bot kicked or blocked")
+	ErrForbidden    = errors.New("forbidden in chat")
+	ErrChatNotFound = errors.New("chat not found")
+)
+
+// Outgoing errors
+var (
+	ErrRetriesExhausted = errors.New("retries exhausted")
+	errWaitTooLong      = errors.New("retry after exceeds max wait")
+	errDecodeFailed     = errors.New("failed to decode sent message")
+)
+
+// Per bot outgoing request layer. Limits message rate per chat
+// and globally, honours retry after, retries transient errors
+// with backoff, and surfaces permanent errors as typed ones.
+type Sender struct {
+	api            *tg.BotAPI
+	chatInterval   time.Duration
+	globalInterval time.Duration
+	maxRetries     int
+	backoff        time.Duration
+	maxWait        time.Duration
+
+	mu     sync.Mutex
+	global time.Time           // Next global slot
+	chats  map[int64]time.Time // Next slot per chat
+}
+
+func New(api *tg.BotAPI, settings *conf.SendingSettings) *Sender {
+	s := &Sender{
+		api:            api,
+		chatInterval:   time.Duration(settings.ChatInterval),
+		globalInterval: time.Duration(settings.GlobalInterval),
+		maxRetries:     settings.MaxRetries,
+		backoff:        time.Duration(settings.Backoff),
+		maxWait:        time.Duration(settings.MaxWait),
+		chats:          make(map[int64]time.Time),
+	}
+
+	// Use defaults if not set
+	if s.chatInterval <= 0 {
+		s.chatInterval = defaultChatInterval
+	}
+	if s.globalInterval <= 0 {
+		s.globalInterval = defaultGlobalInterval
+	}
+	if s.maxRetries <= 0 {
+		s.maxRetries = defaultMaxRetries
+	}
+	if s.backoff <= 0 {
+		s.backoff = defaultBackoff
+	}
+	if s.maxWait <= 0 {
+		s.maxWait = defaultMaxWait
+	}
+
+	return s
+}
+
+// Sends message with method params in chat within send rate limits.
+// Params are used as library configs lack newer fields (e.g. topics).
+func (s *Sender) Send(
+	ctx context.Context, chatID int64, method string, params tg.Params,
+	logger *logging.Logger,
+) (tg.Message, error) {
+	return s.send(ctx, chatID, logger, func() (*tg.APIResponse, error) {
+		return s.api.MakeRequest(method, params)
+	})
+}
+
+// Uploads files as message with method params in chat
+// within send rate limits
+func (s *Sender) Upload(
+	ctx context.Context, chatID int64, method string, params tg.Params,
+	files []tg.RequestFile, logger *logging.Logger,
+) (tg.Message, error) {
+	return s.send(ctx, chatID, logger, func() (*tg.APIResponse, error) {
+		return s.api.UploadFiles(method, params, files)
+	})
+}
+
+// Sends message with call in chat within send rate limits
+func (s *Sender) send(
+	ctx context.Context, chatID int64, logger *logging.Logger,
+	call func() (*tg.APIResponse, error),
+) (tg.Message, error) {
+	var (
+		msg  tg.Message
+		resp *tg.APIResponse
+	)
+	err := s.do(ctx, chatID, true, logger, func() error {
+		var err error
+		resp, err = call()
+		return err
+	})
+	if err != nil {
+		return msg, err
+	}
+
+	// Decode sent message, not retried as already sent
+	err = json.Unmarshal(resp.Result, &msg)
+	if err != nil {
+		return msg, fmt.Errorf("%w: %v", errDecodeFailed, err)
+	}
+	return msg, nil
+}
+
+// Makes request with method params in chat not counted
+// in send rate (e.g. chat action), still waiting out retry after of chat
+func (s *Sender) Request(
+	ctx context.Context, chatID int64, method string, params tg.Params,
+	logger *logging.Logger,
+) error {
+	return s.do(ctx, chatID, false, logger, func() error {
+		_, err := s.api.MakeRequest(method, params)
+		return err
+	})
+}
+
+// Calls API in chat with retries
+func (s *Sender) do(
+	ctx context.Context, chatID int64, limited bool,
+	logger *logging.Logger, call func() error,
+) error {
+	const errMsg = "telegram request failed, retrying"
+
+	var (
+		backoff = s.backoff
+		err     error
+	)
+
+	for attempt := range s.maxRetries + 1 {
+		// Wait for slot
+		werr := sleep(ctx, s.reserve(chatID, limited))
+		if werr != nil {
+			return werr
+		}
+
+		// Call, return on success
+		err = call()
+		if err == nil {
+			return nil
+		}
+
+		// Get retry delay or return permanent error
+		var (
+			delay time.Duration
+			tgErr *tg.Error
+		)
+		switch {
+		case !errors.As(err, &tgErr): // Network
+			delay, backoff = backoff, 2*backoff
+		case tgErr.Code == http.StatusTooManyRequests:
+			delay = time.Duration(tgErr.RetryAfter) * time.Second
+			if delay > s.maxWait {
+				return fmt.Errorf("%w: %v", errWaitTooLong, err)
+			}
+			s.postpone(chatID, delay) // Hold other requests in chat
+		case tgErr.Code >= http.StatusInternalServerError:
+			delay, backoff = backoff, 2*backoff
+		default:
+			return permanent(tgErr)
+		}
+
+		// Log, wait before retry
+		if attempt == s.maxRetries {
+			break
+		}
+		logger.Error(
+			errMsg,
+			logging.Attempt(attempt+1),
+			logging.Duration(delay),
+			logging.Err(err),
+		)
+		werr = sleep(ctx, delay)
+		if werr != nil {
+			return werr
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrRetriesExhausted, err)
+}
+
+// Reserves chat and global slots if limited,
+// gets time to wait for them
+func (s *Sender) reserve(chatID int64, limited bool) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	chatSlot := later(now, s.chats[chatID])
+	if !limited {
+		return chatSlot.Sub(now)
+	}
+	globalSlot := later(now, s.global)
+
+	s.chats[chatID] = chatSlot.Add(s.chatInterval)
+	s.global = globalSlot.Add(s.globalInterval)
+	return later(chatSlot, globalSlot).Sub(now)
+}
+
+// Postpones chat slots for duration
+func (s *Sender) postpone(chatID int64, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[chatID] = later(s.chats[chatID], time.Now().Add(d))
+}
+
+// Gets typed error of permanent Telegram error,
+// the original one if not known
+func permanent(err *tg.Error) error {
+	desc := strings.ToLower(err.Message)
+
+	switch {
+	case err.Code == http.StatusForbidden && isKicked(desc):
+		return fmt.Errorf("%w: %v", ErrBotKicked, err)
+	case err.Code == http.StatusForbidden:
+		return fmt.Errorf("%w: %v", ErrForbidden, err)
+	case strings.Contains(desc, "chat not found"):
+		return fmt.Errorf("%w: %v", ErrChatNotFound, err)
+	default:
+		return err
+	}
+}
+
+// Reports if error description means bot left the chat
+func isKicked(desc string) bool {
+	for _, s := range []string{
+		"kicked", "blocked", "not a member", "deactivated",
+	} {
+		if strings.Contains(desc, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports if error is permanent for chat
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrBotKicked) ||
+		errors.Is(err, ErrForbidden) ||
+		errors.Is(err, ErrChatNotFound)
+}
+
+// Gets the later of times
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// Sleeps for duration or until context done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
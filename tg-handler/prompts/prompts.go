@@ -2,18 +2,34 @@ package prompts
 
 import (
 	"fmt"
+	"maps"
+	"strconv"
+	"strings"
 
 	"tg-handler/conf"
 	"tg-handler/memory"
 	"tg-handler/names"
 )
 
+// Token replaced with reply language name in templates
+const LanguageToken = "{language}"
+
+// Reply placeholder of reflection prompts, unlike verbs
+// it can not come from memory or contacts
+const replyToken = "\x00reply\x00"
+
 // Prompts from formatted templates
 type Prompts struct {
 	Response string
 	Select   string
 	Tags     string
 	Carma    string
+	Reflect  string // Empty if no template
+	Rate     string // Empty if no template
+	Rank     string // Empty if no template
+	Refine   string // Empty if no template
+
+	Vars map[string]string // Built-in pipeline variables
 }
 
 // Formats all prompts from templates incrementally
@@ -22,14 +38,23 @@ func New(
 	memory *memory.Memory,
 	names *names.Names,
 	chatTitle string,
-	candidateNum int,
+	language string,
 ) *Prompts {
+	// Set reply language
+	withLanguage := func(template string) string {
+		return strings.ReplaceAll(template, LanguageToken, language)
+	}
+
 	var (
 		// Get templates
-		responseTemplate = templates.Response
-		selectTemplate   = templates.Select
-		tagsTemplate     = templates.Tags
-		carmaTemplate    = templates.Carma
+		responseTemplate = withLanguage(templates.Response)
+		selectTemplate   = withLanguage(templates.Select)
+		tagsTemplate     = withLanguage(templates.Tags)
+		carmaTemplate    = withLanguage(templates.Carma)
+		reflectTemplate  = withLanguage(templates.Reflect)
+		rateTemplate     = withLanguage(templates.Rate)
+		rankTemplate     = withLanguage(templates.Rank)
+		refineTemplate   = withLanguage(templates.Refine)
 
 		// Get tags limit
 		tagsLimit = memory.Limits.Tags
@@ -40,7 +65,7 @@ func New(
 			responseTemplate, memory, names, chatTitle,
 		),
 		Select: fmtSelectPrompt(
-			selectTemplate, memory, names, candidateNum,
+			selectTemplate, memory, names,
 		),
 		Tags: fmtTagsPrompt(
 			tagsTemplate, memory, names, tagsLimit,
@@ -48,6 +73,24 @@ func New(
 		Carma: fmtCarmaPrompt(
 			carmaTemplate, memory, names,
 		),
+		Reflect: fmtReflectPrompt(
+			reflectTemplate, memory, names, tagsLimit,
+		),
+		Rate: fmtRatePrompt(
+			rateTemplate, memory, names,
+		),
+		Rank: fmtRankPrompt(
+			rankTemplate, memory, names,
+		),
+		Refine: fmtRefinePrompt(
+			refineTemplate, memory, names,
+		),
+		Vars: map[string]string{
+			conf.VarBot:      names.Bot,
+			conf.VarChat:     chatTitle,
+			conf.VarMemory:   memory.String(),
+			conf.VarLanguage: language,
+		},
 	}
 }
 
@@ -64,19 +107,70 @@ func NewNames(bot string, user string) *Names {
 	}
 }
 
-// Finalizes select prompt formatting
-func FinFmtSelectPrompt(prompt string, candidates []string) string {
-	return fmt.Sprintf(prompt, candidates)
+// Finalizes select prompt formatting with numbered candidates,
+// filling the last placeholders as memory may contain verbs
+func FinFmtSelectPrompt(prompt string, candidates string, num int) string {
+	prompt = replaceLast(prompt, "%d", strconv.Itoa(num))
+	return replaceLast(prompt, "%s", candidates)
+}
+
+// Finalizes rate prompt formatting with candidate,
+// filling the last placeholder as memory may contain verbs
+func FinFmtRatePrompt(prompt string, candidate string) string {
+	return replaceLast(prompt, "%s", candidate)
+}
+
+// Finalizes refine prompt formatting with reply,
+// filling the last placeholder as memory may contain verbs
+func FinFmtRefinePrompt(prompt string, reply string) string {
+	return replaceLast(prompt, "%s", reply)
+}
+
+// Formats translate prompt with language codes and text
+func FmtTranslatePrompt(
+	template string, from string, to string, text string,
+) string {
+	return fmt.Sprintf(template, from, to, text)
+}
+
+// Formats describe prompt with image caption
+func FmtDescribePrompt(template string, caption string) string {
+	return fmt.Sprintf(template, caption)
+}
+
+// Formats pipeline step prompt with built-in variables
+// and outputs of previous steps
+func FmtStepPrompt(
+	step *conf.PipelineStep, vars map[string]string,
+) (string, error) {
+	var b strings.Builder
+	if err := step.Tmpl().Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Copies built-in pipeline variables to be extended by steps
+func (p *Prompts) StepVars() map[string]string {
+	return maps.Clone(p.Vars)
 }
 
-// Finalizes tags prompt formatting
+// Finalizes tags prompt formatting,
+// filling reply token as memory may contain verbs
 func FinFmtTagsPrompt(prompt string, replyLine string) string {
-	return fmt.Sprintf(prompt, replyLine)
+	return replaceLast(prompt, replyToken, replyLine)
 }
 
-// Finalizes carma prompt formatting
+// Finalizes carma prompt formatting,
+// filling reply token as memory may contain verbs
 func FinFmtCarmaPrompt(prompt string, replyLine string) string {
-	return fmt.Sprintf(prompt, replyLine)
+	return replaceLast(prompt, replyToken, replyLine)
+}
+
+// Finalizes reflect prompt formatting,
+// filling reply token as memory may contain verbs
+func FinFmtReflectPrompt(prompt string, replyLine string) string {
+	return replaceLast(prompt, replyToken, replyLine)
 }
 
 // Formats response prompt
@@ -98,14 +192,15 @@ func fmtSelectPrompt(
 	template string,
 	memory *memory.Memory,
 	names *names.Names,
-	candidateNum int,
 ) string {
 	var botName = names.Bot
 
+	// Escape candidate number placeholder
+	template = strings.Replace(template, "%d", "%%d", 1)
+
 	return fmt.Sprintf(template,
 		botName, memory,
 		"%s", // Response candidates placeholder
-		candidateNum,
 	)
 }
 
@@ -124,7 +219,7 @@ func fmtTagsPrompt(
 
 	return fmt.Sprintf(template,
 		userName, botName, memory,
-		"%s", // Final response placeholder
+		replyToken, // Final response placeholder
 		userName, contact.Tags,
 		userName, lim,
 	)
@@ -145,7 +240,95 @@ func fmtCarmaPrompt(
 
 	return fmt.Sprintf(template,
 		userName, botName, memory,
-		"%s", // Final response placeholder
+		replyToken, // Final response placeholder
 		userName, contact.Carma,
 	)
 }
+
+// Formats reflect prompt incrementally
+func fmtReflectPrompt(
+	template string,
+	memory *memory.Memory,
+	names *names.Names,
+	lim int,
+) string {
+	// Handle optional template
+	if template == "" {
+		return ""
+	}
+
+	var (
+		botName  = names.Bot
+		userName = names.User
+		contact  = memory.BotContacts.Get(userName)
+	)
+
+	return fmt.Sprintf(template,
+		userName, botName, memory,
+		replyToken, // Final response placeholder
+		userName, contact.Carma,
+		userName, contact.Tags,
+		lim,
+	)
+}
+
+// Formats rate prompt incrementally
+func fmtRatePrompt(
+	template string,
+	memory *memory.Memory,
+	names *names.Names,
+) string {
+	// Handle optional template
+	if template == "" {
+		return ""
+	}
+
+	var botName = names.Bot
+
+	return fmt.Sprintf(template,
+		botName, memory,
+		"%s", // Response candidate placeholder
+	)
+}
+
+// Formats rank prompt incrementally as select one
+func fmtRankPrompt(
+	template string,
+	memory *memory.Memory,
+	names *names.Names,
+) string {
+	// Handle optional template
+	if template == "" {
+		return ""
+	}
+
+	return fmtSelectPrompt(template, memory, names)
+}
+
+// Formats refine prompt incrementally
+func fmtRefinePrompt(
+	template string,
+	memory *memory.Memory,
+	names *names.Names,
+) string {
+	// Handle optional template
+	if template == "" {
+		return ""
+	}
+
+	var botName = names.Bot
+
+	return fmt.Sprintf(template,
+		botName, memory,
+		"%s", // Reply placeholder
+	)
+}
+
+// Replaces the last occurrence of placeholder
+func replaceLast(s string, placeholder string, value string) string {
+	i := strings.LastIndex(s, placeholder)
+	if i == -1 {
+		return s
+	}
+	return s[:i] + value + s[i+len(placeholder):]
+}
@@ -0,0 +1,68 @@
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"tg-handler/schema"
+)
+
+const (
+	Min = 1
+	Max = 10
+)
+
+// Rating errors
+var (
+	errRatingNaN = errors.New("rating is not a number")
+	errRatingOOB = fmt.Errorf(
+		"rating is out of bounds %d-%d", Min, Max,
+	)
+)
+
+type Rating int
+
+// Structured rating output
+type Score struct {
+	Score int `json:"score" min:"1" max:"10"`
+}
+
+// Schema for structured rating output
+var Schema = schema.MustOf(Score{})
+
+// Sequence of digits
+var numRe = regexp.MustCompile(`\d+`)
+
+// Parses structured output, falls back to free text
+func Parse(s string) (Rating, error) {
+	var score Score
+	if err := schema.Decode(s, &score); err != nil {
+		return New(s)
+	}
+	return newRating(score.Score)
+}
+
+// Parses free text taking the first number
+func New(s string) (Rating, error) {
+	match := numRe.FindString(s)
+	if match == "" {
+		return 0, errRatingNaN
+	}
+
+	num, err := strconv.Atoi(match)
+	if err != nil {
+		return 0, errRatingNaN
+	}
+
+	return newRating(num)
+}
+
+// Constructs rating abiding bounds
+func newRating(n int) (Rating, error) {
+	if n < Min || n > Max {
+		return 0, errRatingOOB
+	}
+	return Rating(n), nil
+}
@@ -0,0 +1,275 @@
+package reflection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"tg-handler/logging"
+)
+
+// Queue errors
+var (
+	errReadFailed      = errors.New("failed to read queue")
+	errUnmarshalFailed = errors.New("failed to unmarshal queue")
+	errMarshalFailed   = errors.New("failed to marshal queue")
+	errWriteFailed     = errors.New("failed to write queue")
+)
+
+// Pending reflection on user after bot replies,
+// persisted to be done after restart
+type Job struct {
+	ChatID    int64    `json:"chat_id"`
+	ThreadID  int      `json:"thread_id,omitempty"` // Forum topic
+	ChatTitle string   `json:"chat_title"`
+	User      string   `json:"user"`
+	UserLine  string   `json:"user_line"`   // Last user message
+	Lines     []string `json:"reply_lines"` // Bot replies batched
+}
+
+func NewJob(
+	chatID int64, threadID int, chatTitle string, user string,
+	userLine string, replyLine string,
+) *Job {
+	return &Job{
+		ChatID:    chatID,
+		ThreadID:  threadID,
+		ChatTitle: chatTitle,
+		User:      user,
+		UserLine:  userLine,
+		Lines:     []string{replyLine},
+	}
+}
+
+// Last reply line exposed
+func (j *Job) Line() string {
+	return j.Lines[len(j.Lines)-1]
+}
+
+// Last user message line exposed
+func (j *Job) PrevLine() string {
+	return j.UserLine
+}
+
+// All reply lines batched
+func (j *Job) Replies() string {
+	return strings.Join(j.Lines, "\n")
+}
+
+// Reports if jobs reflect on the same user in the same chat topic
+func (j *Job) batches(other *Job) bool {
+	return j.ChatID == other.ChatID &&
+		j.ThreadID == other.ThreadID &&
+		j.User == other.User
+}
+
+// Merges later job of the same chat topic and user into job
+func (j *Job) merge(later *Job) {
+	j.ChatTitle = later.ChatTitle
+	j.UserLine = later.UserLine
+	j.Lines = append(j.Lines, later.Lines...)
+}
+
+// Persistent reflection queue with worker pool.
+// Jobs for the same user in the same chat topic are batched
+// while pending, jobs for the same user are never performed
+// concurrently (contacts are chat agnostic). Replies go first
+// by model request priority, not by holding workers.
+type Queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	path    string
+	pending []*Job          // Waiting jobs in order
+	running map[string]*Job // Performed jobs by user
+	logger  *logging.Logger
+}
+
+// Loads queue from path, empty if file does not exist
+func LoadQueue(path string, logger *logging.Logger) *Queue {
+	const errMsg = "failed to load reflection queue"
+	logger = logger.With(logging.Path(path))
+
+	q := &Queue{
+		path:    path,
+		running: make(map[string]*Job),
+		logger:  logger,
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	// Try to read file
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q
+	} else if err != nil {
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errReadFailed, err),
+		))
+		return q
+	}
+
+	// Unmarshal
+	if err := json.Unmarshal(data, &q.pending); err != nil {
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errUnmarshalFailed, err),
+		))
+		return q
+	}
+
+	logger.Info(
+		"reflection queue loaded", logging.QueueLen(len(q.pending)),
+	)
+	return q
+}
+
+// Pushes job, batching it with pending job
+// of the same user in the same chat topic
+func (q *Queue) Push(job *Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Batch with pending job
+	for _, pending := range q.pending {
+		if pending.batches(job) {
+			pending.merge(job)
+			q.logger.Debug(
+				"reflection batched", logging.UserName(job.User),
+			)
+			q.save()
+			q.cond.Signal()
+			return
+		}
+	}
+
+	// Add new job
+	q.pending = append(q.pending, job)
+	q.save()
+	q.cond.Signal()
+}
+
+// Performs jobs with workers until context done,
+// jobs interrupted by shutdown stay persisted
+func (q *Queue) Run(
+	ctx context.Context,
+	workers int,
+	perform func(context.Context, *Job) error,
+) {
+	// Wake workers on context done
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			q.work(ctx, perform)
+		})
+	}
+	wg.Wait()
+
+	q.logger.Info("reflection queue shut down gracefully")
+}
+
+// Takes and performs jobs until context done
+func (q *Queue) work(
+	ctx context.Context,
+	perform func(context.Context, *Job) error,
+) {
+	for {
+		job := q.take(ctx)
+		if job == nil {
+			return
+		}
+
+		err := perform(ctx, job)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			q.logger.Error(
+				"reflection failed",
+				logging.UserName(job.User),
+				logging.Err(err),
+			)
+		}
+
+		q.done(job)
+	}
+}
+
+// Waits for job to take, nil if context done
+func (q *Queue) take(ctx context.Context) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Take first job of idle user
+		for i, job := range q.pending {
+			if _, ok := q.running[job.User]; ok {
+				continue
+			}
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.running[job.User] = job
+			return job
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// Removes performed job
+func (q *Queue) done(job *Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.running, job.User)
+	q.save()
+	q.cond.Broadcast()
+}
+
+// Saves running and pending jobs (lock held)
+func (q *Queue) save() {
+	const errMsg = "failed to save reflection queue"
+
+	// Keep running jobs first to redo them first
+	jobs := make([]*Job, 0, len(q.running)+len(q.pending))
+	for _, job := range q.running {
+		jobs = append(jobs, job)
+	}
+	jobs = append(jobs, q.pending...)
+
+	// Marshal
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		q.logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errMarshalFailed, err),
+		))
+		return
+	}
+
+	// Write atomically via temporary file
+	tmpPath := q.path + ".tmp"
+	err = os.MkdirAll(filepath.Dir(q.path), 0755)
+	if err == nil {
+		err = os.WriteFile(tmpPath, data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, q.path)
+	}
+	if err != nil {
+		q.logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errWriteFailed, err),
+		))
+	}
+}
@@ -0,0 +1,83 @@
+package reflection
+
+import (
+	"errors"
+	"strings"
+
+	"tg-handler/carma"
+	"tg-handler/logging"
+	"tg-handler/schema"
+	"tg-handler/tags"
+)
+
+// Structured combined reflection output
+type Output struct {
+	Carma     string   `json:"carma" enum:"-,=,+"`
+	Tags      []string `json:"tags"`
+	Rationale string   `json:"rationale"`
+}
+
+// Schema for structured combined reflection output
+var Schema = schema.MustOf(Output{})
+
+// Reflection errors
+var errEmptyReflection = errors.New("empty reflection")
+
+// Reflection on user from one call
+type Reflection struct {
+	CarmaUpdate carma.Update
+	Tags        tags.Tags
+	Rationale   string
+}
+
+// Parses structured output, falls back to free text
+func Parse(
+	s string, lim int, logger *logging.Logger,
+) (*Reflection, error) {
+	var output Output
+	if err := schema.Decode(s, &output); err != nil {
+		return parseFree(s, lim, logger)
+	}
+
+	// Get carma update
+	carmaUpdate, err := carma.NewUpdate(output.Carma)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get tags
+	tags, err := tags.FromList(output.Tags, lim, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reflection{
+		CarmaUpdate: carmaUpdate,
+		Tags:        tags,
+		Rationale:   strings.TrimSpace(output.Rationale),
+	}, nil
+}
+
+// Parses free text: carma sign followed by tags, e.g. "+ #kind #curious"
+func parseFree(
+	s string, lim int, logger *logging.Logger,
+) (*Reflection, error) {
+	sign, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	if sign == "" {
+		return nil, errEmptyReflection
+	}
+
+	// Get carma update
+	carmaUpdate, err := carma.NewUpdate(sign)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get tags
+	tags, err := tags.New(strings.TrimSpace(rest), lim, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reflection{CarmaUpdate: carmaUpdate, Tags: tags}, nil
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"html"
+	"strings"
+)
+
+// Writes nodes in target format
+type emitter interface {
+	text(s string) string
+	code(s string) string
+	pre(s string, lang string) string
+	link(inner string, url string) string
+	wrap(kind nodeKind, inner string) string
+}
+
+// Telegram HTML emitter
+type htmlEmitter struct{}
+
+func (htmlEmitter) text(s string) string {
+	return html.EscapeString(s)
+}
+
+func (htmlEmitter) code(s string) string {
+	return "<code>" + html.EscapeString(s) + "</code>"
+}
+
+func (htmlEmitter) pre(s string, lang string) string {
+	if lang == "" {
+		return "<pre>" + html.EscapeString(s) + "</pre>"
+	}
+	return `<pre><code class="language-` + html.EscapeString(lang) +
+		`">` + html.EscapeString(s) + "</code></pre>"
+}
+
+func (htmlEmitter) link(inner string, url string) string {
+	return `<a href="` + html.EscapeString(url) + `">` + inner + "</a>"
+}
+
+func (htmlEmitter) wrap(kind nodeKind, inner string) string {
+	tag := htmlTags[kind]
+	return "<" + tag + ">" + inner + "</" + tag + ">"
+}
+
+var htmlTags = map[nodeKind]string{
+	nodeBold:   "b",
+	nodeItalic: "i",
+	nodeStrike: "s",
+}
+
+// Telegram MarkdownV2 emitter
+type markdownV2Emitter struct{}
+
+// Escapers for MarkdownV2 contexts
+var (
+	mdV2TextEscaper = escaperOf("_*[]()~`>#+-=|{}.!\\")
+	mdV2CodeEscaper = escaperOf("`\\")
+	mdV2URLEscaper  = escaperOf(")\\")
+)
+
+func (markdownV2Emitter) text(s string) string {
+	return mdV2TextEscaper.Replace(s)
+}
+
+func (markdownV2Emitter) code(s string) string {
+	return "`" + mdV2CodeEscaper.Replace(s) + "`"
+}
+
+func (markdownV2Emitter) pre(s string, lang string) string {
+	return "```" + lang + "\n" + mdV2CodeEscaper.Replace(s) + "\n```"
+}
+
+func (markdownV2Emitter) link(inner string, url string) string {
+	return "[" + inner + "](" + mdV2URLEscaper.Replace(url) + ")"
+}
+
+func (markdownV2Emitter) wrap(kind nodeKind, inner string) string {
+	mark := mdV2Marks[kind]
+	return mark + inner + mark
+}
+
+var mdV2Marks = map[nodeKind]string{
+	nodeBold:   "*",
+	nodeItalic: "_",
+	nodeStrike: "~",
+}
+
+// Constructs replacer prefixing characters with backslash
+func escaperOf(chars string) *strings.Replacer {
+	pairs := make([]string, 0, 2*len(chars))
+	for _, c := range chars {
+		pairs = append(pairs, string(c), `\`+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of Markdown nodes
+type nodeKind int
+
+const (
+	nodeText nodeKind = iota
+	nodeBold
+	nodeItalic
+	nodeStrike
+	nodeCode
+	nodePre
+	nodeLink
+)
+
+// Markdown node, text for text and code, children otherwise
+type node struct {
+	kind     nodeKind
+	text     string
+	url      string // Link URL or code block language
+	children []node
+}
+
+// Paired delimiters in order of matching
+var delimiters = []struct {
+	mark string
+	kind nodeKind
+}{
+	{"**", nodeBold},
+	{"__", nodeBold},
+	{"~~", nodeStrike},
+	{"*", nodeItalic},
+	{"_", nodeItalic},
+}
+
+// Parses inline Markdown, unpaired marks stay text
+func parseInline(s string) []node {
+	var (
+		nodes []node
+		text  strings.Builder
+	)
+
+	// Flush accumulated text as node
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, node{kind: nodeText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		n, size := parseAt(s, i)
+		if size > 0 {
+			flush()
+			nodes = append(nodes, n)
+			i += size
+			continue
+		}
+
+		// Take rune as text
+		r, width := utf8.DecodeRuneInString(s[i:])
+		text.WriteRune(r)
+		i += width
+	}
+	flush()
+
+	return nodes
+}
+
+// Parses node starting at index, size 0 if none
+func parseAt(s string, i int) (node, int) {
+	rest := s[i:]
+
+	// Code spans
+	if strings.HasPrefix(rest, "`") {
+		end := strings.Index(rest[1:], "`")
+		if end > 0 {
+			return node{kind: nodeCode, text: rest[1 : end+1]}, end + 2
+		}
+		return node{}, 0
+	}
+
+	// Links
+	if strings.HasPrefix(rest, "[") {
+		return parseLink(rest)
+	}
+
+	// Paired delimiters
+	for _, d := range delimiters {
+		if !strings.HasPrefix(rest, d.mark) {
+			continue
+		}
+
+		// Skip intraword underscores as in snake_case
+		if d.mark[0] == '_' && isWordBefore(s, i) {
+			return node{}, 0
+		}
+
+		inner, ok := closing(rest[len(d.mark):], d.mark)
+		if !ok {
+			continue
+		}
+		return node{
+			kind:     d.kind,
+			children: parseInline(inner),
+		}, len(inner) + 2*len(d.mark)
+	}
+
+	return node{}, 0
+}
+
+// Gets text before closing mark, not empty nor space padded
+func closing(s string, mark string) (string, bool) {
+	end := strings.Index(s, mark)
+	if end <= 0 {
+		return "", false
+	}
+
+	inner := s[:end]
+	if strings.TrimSpace(inner) != inner {
+		return "", false
+	}
+	// Skip italic closing as part of bold one
+	if len(mark) == 1 && strings.HasPrefix(s[end+1:], mark) {
+		return "", false
+	}
+	return inner, true
+}
+
+// Parses [text](url) link
+func parseLink(s string) (node, int) {
+	textEnd := strings.Index(s, "](")
+	if textEnd <= 1 {
+		return node{}, 0
+	}
+	urlEnd := strings.Index(s[textEnd+2:], ")")
+	if urlEnd <= 0 {
+		return node{}, 0
+	}
+
+	url := s[textEnd+2 : textEnd+2+urlEnd]
+	if strings.ContainsAny(url, " \n") {
+		return node{}, 0
+	}
+	return node{
+		kind:     nodeLink,
+		url:      url,
+		children: parseInline(s[1:textEnd]),
+	}, textEnd + 2 + urlEnd + 1
+}
+
+// Reports if rune before index is a letter or digit
+func isWordBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"slices"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"tg-handler/conf"
+)
+
+// Gets Telegram parse mode of format, empty for plain
+func ParseMode(format string) string {
+	switch format {
+	case conf.FormatHTML:
+		return tg.ModeHTML
+	case conf.FormatMarkdownV2:
+		return tg.ModeMarkdownV2
+	default:
+		return ""
+	}
+}
+
+// Renders model Markdown in format, unknown formats stay plain
+func Render(text string, format string) string {
+	var e emitter
+	switch format {
+	case conf.FormatHTML:
+		e = htmlEmitter{}
+	case conf.FormatMarkdownV2:
+		e = markdownV2Emitter{}
+	default:
+		return text
+	}
+
+	var sb strings.Builder
+	writeNodes(&sb, e, parseBlocks(text))
+	return sb.String()
+}
+
+// Parses fenced code blocks and lines, unclosed fences stay text
+func parseBlocks(text string) []node {
+	var (
+		nodes []node
+		lines = strings.Split(text, "\n")
+	)
+
+	for i := 0; i < len(lines); i++ {
+		if i > 0 {
+			nodes = append(nodes, node{kind: nodeText, text: "\n"})
+		}
+
+		// Take fenced code block
+		if lang, ok := strings.CutPrefix(lines[i], "```"); ok {
+			end := slices.IndexFunc(lines[i+1:], isFence)
+			if end != -1 {
+				nodes = append(nodes, node{
+					kind: nodePre,
+					text: strings.Join(lines[i+1:i+1+end], "\n"),
+					url:  strings.TrimSpace(lang),
+				})
+				i += end + 1
+				continue
+			}
+		}
+
+		nodes = append(nodes, parseLine(lines[i])...)
+	}
+
+	return nodes
+}
+
+// Reports if line closes fenced code block
+func isFence(line string) bool {
+	return strings.TrimSpace(line) == "```"
+}
+
+// Gets nodes of line, headings become bold
+func parseLine(line string) []node {
+	if heading, ok := cutHeading(line); ok {
+		return []node{{
+			kind:     nodeBold,
+			children: parseInline(heading),
+		}}
+	}
+	return parseInline(line)
+}
+
+// Gets heading text if line is heading
+func cutHeading(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, "#")
+	if trimmed != line && strings.HasPrefix(trimmed, " ") {
+		return strings.TrimSpace(trimmed), true
+	}
+	return "", false
+}
+
+// Writes nodes with emitter
+func writeNodes(sb *strings.Builder, e emitter, nodes []node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case nodeText:
+			sb.WriteString(e.text(n.text))
+		case nodeCode:
+			sb.WriteString(e.code(n.text))
+		case nodePre:
+			sb.WriteString(e.pre(n.text, n.url))
+		case nodeLink:
+			var inner strings.Builder
+			writeNodes(&inner, e, n.children)
+			sb.WriteString(e.link(inner.String(), n.url))
+		default:
+			var inner strings.Builder
+			writeNodes(&inner, e, n.children)
+			sb.WriteString(e.wrap(n.kind, inner.String()))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"testing"
+
+	"tg-handler/conf"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "hello", "hello"},
+		{"escaped", `a < b & "c" > d`, "a &lt; b &amp; &#34;c&#34; &gt; d"},
+		{"bold", "**a** and __b__", "<b>a</b> and <b>b</b>"},
+		{"italic", "*a* _b_", "<i>a</i> <i>b</i>"},
+		{"strike", "~~a~~", "<s>a</s>"},
+		{"nested", "**a _b_**", "<b>a <i>b</i></b>"},
+		{"snake case", "snake_case_name", "snake_case_name"},
+		{"unpaired", "2 * 3", "2 * 3"},
+		{"code escaped", "`<b>`", "<code>&lt;b&gt;</code>"},
+		{"code not parsed", "`**a**`", "<code>**a**</code>"},
+		{
+			"link",
+			"[a & b](https://x.io/?q=1&r=2)",
+			`<a href="https://x.io/?q=1&amp;r=2">a &amp; b</a>`,
+		},
+		{"heading", "## Title <1>", "<b>Title &lt;1&gt;</b>"},
+		{
+			"code block",
+			"```go\nif a < b {}\n```",
+			`<pre><code class="language-go">if a &lt; b {}</code></pre>`,
+		},
+		{"code block no lang", "```\n**a**\n```", "<pre>**a**</pre>"},
+		{"unclosed fence", "```\na", "```\na"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.text, conf.FormatHTML)
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "hello", "hello"},
+		{"escaped", "a.b!c-d(e)", `a\.b\!c\-d\(e\)`},
+		{"all specials", "_*[]()~`>#+-=|{}.!\\", `\_\*\[\]\(\)\~` +
+			"\\`" + `\>\#\+\-\=\|\{\}\.\!\\`},
+		{"bold", "**a.b**", `*a\.b*`},
+		{"italic", "_a_", "_a_"},
+		{"strike", "~~a~~", "~a~"},
+		{"snake case", "snake_case", `snake\_case`},
+		{"code escaped", "`a\\b.c`", "`a\\\\b.c`"},
+		{"link", `[a.b](https://x.io/a\b)`, `[a\.b](https://x.io/a\\b)`},
+		{"heading", "# Hi!", `*Hi\!*`},
+		{"code block", "```py\nx = 1.0\n```", "```py\nx = 1.0\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.text, conf.FormatMarkdownV2)
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	const text = "**a** <b> _c_"
+	if got := Render(text, conf.FormatPlain); got != text {
+		t.Errorf("Render(%q) = %q, want unchanged", text, got)
+	}
+}
@@ -0,0 +1,208 @@
+package render
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Telegram message length limit in UTF-16 code units
+const MaxLen = 4096
+
+// Boundaries to split at in order of preference
+var boundaries = []string{"\n\n", "\n", ". ", "! ", "? ", " "}
+
+// Closing of split code block
+const fenceClose = "\n```"
+
+// Byte range of Markdown node not to be cut
+type span struct {
+	start int
+	end   int
+	lang  string // Code block language
+	fence bool   // Fenced code block
+}
+
+// Splits text into parts within limit at paragraph,
+// line, sentence or word boundaries outside Markdown nodes.
+// Oversized code blocks are split into several ones,
+// other oversized nodes and words are cut.
+func Split(text string, limit int) []string {
+	var parts []string
+
+	text = strings.TrimSpace(text)
+	for text != "" {
+		// Take the rest within limit
+		end := prefixEnd(text, limit)
+		if end == len(text) {
+			parts = append(parts, text)
+			break
+		}
+
+		// Cut at the last boundary outside nodes if any
+		spans := spansOf(text)
+		cut := lastBoundary(text[:end], spans)
+		if cut == 0 {
+			// Split code block at line
+			s, ok := enclosing(spans, end)
+			if ok && s.fence {
+				part, rest, ok := splitFence(text, s, limit)
+				if ok {
+					parts = append(parts, part)
+					text = rest
+					continue
+				}
+			}
+
+			// Cut node or word
+			cut = lastBoundary(text[:end], nil)
+			if cut == 0 {
+				cut = end
+			}
+		}
+
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+
+	return parts
+}
+
+// Gets number of UTF-16 code units of text
+func Len(text string) int {
+	var units int
+	for _, r := range text {
+		units += utf16Len(r)
+	}
+	return units
+}
+
+// Gets index after the last boundary outside spans, 0 if none
+func lastBoundary(text string, spans []span) int {
+	for _, boundary := range boundaries {
+		for end := len(text); ; {
+			i := strings.LastIndex(text[:end], boundary)
+			if i <= 0 {
+				break
+			}
+			cut := i + len(strings.TrimRight(boundary, " \n"))
+			if _, ok := enclosing(spans, cut); !ok {
+				return cut
+			}
+			end = i
+		}
+	}
+	return 0
+}
+
+// Gets span strictly enclosing index
+func enclosing(spans []span, i int) (span, bool) {
+	for _, s := range spans {
+		if s.start < i && i < s.end {
+			return s, true
+		}
+	}
+	return span{}, false
+}
+
+// Splits oversized code block at the last line within limit,
+// closing it in part and reopening in rest
+func splitFence(text string, s span, limit int) (string, string, bool) {
+	var (
+		bodyStart  = s.start + strings.Index(text[s.start:], "\n") + 1
+		closeStart = s.start + strings.LastIndex(text[s.start:s.end], "\n")
+		end        = min(prefixEnd(text, limit-Len(fenceClose)), closeStart)
+	)
+	if end <= bodyStart {
+		return "", "", false
+	}
+
+	// Cut at line, at limit if none
+	cut, next := end, end
+	if i := strings.LastIndex(text[bodyStart:end], "\n"); i > 0 {
+		cut, next = bodyStart+i, bodyStart+i+1
+	}
+
+	part := text[:cut] + fenceClose
+	rest := "```" + s.lang + "\n" + text[next:]
+	return part, rest, true
+}
+
+// Gets spans of nodes as parsed for rendering
+func spansOf(text string) []span {
+	var (
+		spans []span
+		lines = strings.Split(text, "\n")
+		start int
+	)
+
+	for i := 0; i < len(lines); i++ {
+		// Take fenced code block
+		if lang, ok := strings.CutPrefix(lines[i], "```"); ok {
+			end := slices.IndexFunc(lines[i+1:], isFence)
+			if end != -1 {
+				size := len(strings.Join(lines[i:i+end+2], "\n"))
+				spans = append(spans, span{
+					start: start,
+					end:   start + size,
+					lang:  strings.TrimSpace(lang),
+					fence: true,
+				})
+				start += size + 1
+				i += end + 1
+				continue
+			}
+		}
+
+		spans = append(spans, lineSpans(lines[i], start)...)
+		start += len(lines[i]) + 1
+	}
+
+	return spans
+}
+
+// Gets spans of nodes in line at offset, headings are whole
+func lineSpans(line string, offset int) []span {
+	if _, ok := cutHeading(line); ok {
+		return []span{{start: offset, end: offset + len(line)}}
+	}
+
+	var spans []span
+	for i := 0; i < len(line); {
+		_, size := parseAt(line, i)
+		if size > 0 {
+			spans = append(spans, span{
+				start: offset + i,
+				end:   offset + i + size,
+			})
+			i += size
+			continue
+		}
+
+		_, width := utf8.DecodeRuneInString(line[i:])
+		i += width
+	}
+
+	return spans
+}
+
+// Gets byte index ending the longest prefix within limit
+func prefixEnd(s string, limit int) int {
+	var units int
+	for i, r := range s {
+		units += utf16Len(r)
+		if units > limit {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// Gets number of UTF-16 code units of rune
+func utf16Len(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1 // Replacement character
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "empty",
+			text:  "  \n ",
+			limit: 10,
+			want:  nil,
+		},
+		{
+			name:  "within limit",
+			text:  " short text\n",
+			limit: 10,
+			want:  []string{"short text"},
+		},
+		{
+			name:  "paragraph first",
+			text:  "one two\n\nthree four",
+			limit: 14,
+			want:  []string{"one two", "three four"},
+		},
+		{
+			name:  "sentence before word",
+			text:  "One. Two three",
+			limit: 12,
+			want:  []string{"One.", "Two three"},
+		},
+		{
+			name:  "word cut if no boundary",
+			text:  "abcdefgh",
+			limit: 3,
+			want:  []string{"abc", "def", "gh"},
+		},
+		{
+			name:  "surrogate pairs count twice",
+			text:  "😀😀😀",
+			limit: 4,
+			want:  []string{"😀😀", "😀"},
+		},
+		{
+			name:  "BMP runes count once",
+			text:  "ёжик ёжик",
+			limit: 4,
+			want:  []string{"ёжик", "ёжик"},
+		},
+		{
+			name:  "bold kept whole",
+			text:  "a **b c**",
+			limit: 8,
+			want:  []string{"a", "**b c**"},
+		},
+		{
+			name:  "link kept whole",
+			text:  "see [a b](u) now",
+			limit: 13,
+			want:  []string{"see [a b](u)", "now"},
+		},
+		{
+			name:  "code block kept whole",
+			text:  "intro\n```\na b\n```",
+			limit: 14,
+			want:  []string{"intro", "```\na b\n```"},
+		},
+		{
+			name:  "oversized code block split at line",
+			text:  "```go\nx\ny\n```",
+			limit: 12,
+			want:  []string{"```go\nx\n```", "```go\ny\n```"},
+		},
+		{
+			name:  "oversized bold cut at word",
+			text:  "**a b c**",
+			limit: 6,
+			want:  []string{"**a b", "c**"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Split(%q, %d) = %q, want %q",
+					tt.text, tt.limit, got, tt.want)
+			}
+			for _, part := range got {
+				if Len(part) > tt.limit {
+					t.Errorf("part %q over limit %d", part, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitLongCodeBlock(t *testing.T) {
+	lines := slices.Repeat([]string{strings.Repeat("x", 40)}, 300)
+	text := "```\n" + strings.Join(lines, "\n") + "\n```"
+
+	for _, part := range Split(text, MaxLen) {
+		if Len(part) > MaxLen {
+			t.Errorf("part of %d units over limit", Len(part))
+		}
+		if !strings.HasPrefix(part, "```\n") ||
+			!strings.HasSuffix(part, "\n```") {
+			t.Errorf("part not fenced: %.20q…", part)
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"ёж", 2},
+		{"😀", 2},
+		{"a😀b", 4},
+	}
+
+	for _, tt := range tests {
+		if got := Len(tt.text); got != tt.want {
+			t.Errorf("Len(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
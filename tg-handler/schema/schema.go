@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema errors
+var (
+	errNotStruct       = errors.New("schema source is not a struct")
+	errUnsupportedKind = errors.New("unsupported field kind")
+	errDecodeFailed    = errors.New("decode structured output failed")
+	errUnknownField    = errors.New("unknown schema field")
+)
+
+// JSON schema subset understood by Ollama structured outputs
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Minimum    *int               `json:"minimum,omitempty"`
+	Maximum    *int               `json:"maximum,omitempty"`
+}
+
+// Constructs JSON schema from struct value or panics.
+// Field names are taken from `json` tags; `enum:"a,b"`,
+// `min:"n"`, `max:"n"` tags restrict values.
+func MustOf(v any) json.RawMessage {
+	return mustMarshal(mustOfValue(v))
+}
+
+// Constructs JSON schema from struct value with integer field
+// (items of array field) bounded by maximum known at runtime
+// (e.g. candidate number), or panics
+func MustOfMax(v any, field string, max int) json.RawMessage {
+	s := mustOfValue(v)
+
+	fs, ok := s.Properties[field]
+	if !ok {
+		panic(fmt.Errorf("%w: %s", errUnknownField, field))
+	}
+	if fs.Items != nil {
+		fs = fs.Items
+	}
+	fs.Maximum = &max
+
+	return mustMarshal(s)
+}
+
+// Constructs schema for struct value or panics
+func mustOfValue(v any) *Schema {
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Struct {
+		panic(errNotStruct)
+	}
+	return mustOfType(t)
+}
+
+// Encodes schema or panics
+func mustMarshal(s *Schema) json.RawMessage {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Constructs schema for type or panics
+func mustOfType(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: mustOfType(t.Elem())}
+	case reflect.Struct:
+		return mustOfStruct(t)
+	default:
+		panic(fmt.Errorf("%w: %v", errUnsupportedKind, t.Kind()))
+	}
+}
+
+// Constructs object schema with all fields required
+func mustOfStruct(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema, t.NumField()),
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		// Get field schema with restrictions
+		fs := mustOfType(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			fs.Enum = strings.Split(enum, ",")
+		}
+		fs.Minimum = intTag(field.Tag.Get("min"))
+		fs.Maximum = intTag(field.Tag.Get("max"))
+
+		s.Properties[name] = fs
+		s.Required = append(s.Required, name)
+	}
+
+	return s
+}
+
+// Gets integer from tag value if set
+func intTag(v string) *int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// Decodes structured output into value, rejecting unknown fields
+func Decode(s string, v any) error {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errDecodeFailed, err)
+	}
+	return nil
+}
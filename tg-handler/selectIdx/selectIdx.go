@@ -1,10 +1,13 @@
 package selectIdx
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
+
+	"tg-handler/schema"
 )
 
 // Selection errors
@@ -15,14 +18,96 @@ var (
 	ErrSelectIdxOOB = errors.New(
 		"select index is out of bounds",
 	)
+	ErrRankingIncomplete = errors.New(
+		"ranking misses candidates",
+	)
 )
 
 type SelectIdx int
 
+// Sequence of digits
+var numRe = regexp.MustCompile(`\d+`)
+
+// Structured selection output
+type Choice struct {
+	Best int `json:"best" min:"1"`
+}
+
+// Gets schema for structured selection output
+// bounded by candidate number
+func SchemaFor(lim int) json.RawMessage {
+	return schema.MustOfMax(Choice{}, "best", lim)
+}
+
+// Parses structured output, falls back to free text
+func Parse(s string, lim int) (SelectIdx, error) {
+	var choice Choice
+	if err := schema.Decode(s, &choice); err != nil {
+		return New(s, lim)
+	}
+	return newIdx(choice.Best, lim)
+}
+
+// Structured ranking output, best first
+type Ranking struct {
+	Ranking []int `json:"ranking"`
+}
+
+// Gets schema for structured ranking output
+// bounded by candidate number
+func RankingSchemaFor(lim int) json.RawMessage {
+	return schema.MustOfMax(Ranking{}, "ranking", lim)
+}
+
+// Parses structured ranking, falls back to numbers in free text.
+// Duplicates are skipped, ranking must include all candidates.
+func ParseRanking(s string, lim int) ([]SelectIdx, error) {
+	var (
+		ranking Ranking
+		nums    []int
+	)
+	if err := schema.Decode(s, &ranking); err == nil {
+		nums = ranking.Ranking
+	} else {
+		for _, match := range numRe.FindAllString(s, -1) {
+			num, err := strconv.Atoi(match)
+			if err != nil {
+				return nil, ErrSelectNumNaN
+			}
+			nums = append(nums, num)
+		}
+	}
+
+	// Accumulate unique indices
+	idxs := make([]SelectIdx, 0, len(nums))
+	seen := make(map[SelectIdx]bool, len(nums))
+	for _, num := range nums {
+		idx, err := newIdx(num, lim)
+		if err != nil {
+			return nil, err
+		}
+		if seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		idxs = append(idxs, idx)
+	}
+
+	// Check if non-zero indices, all candidates ranked
+	if len(idxs) < 1 {
+		return nil, ErrSelectNumNaN
+	}
+	if len(idxs) < lim {
+		return nil, ErrRankingIncomplete
+	}
+
+	return idxs, nil
+}
+
+// Parses free text taking the first number
 func New(s string, lim int) (SelectIdx, error) {
-	// Compile regex to find the first sequence of digits
-	re := regexp.MustCompile(`\d+`)
-	match := re.FindString(s)
+	// Find the first sequence of digits
+	match := numRe.FindString(s)
 	if match == "" {
 		return 0, ErrSelectNumNaN
 	}
@@ -33,6 +118,11 @@ func New(s string, lim int) (SelectIdx, error) {
 		return 0, ErrSelectNumNaN
 	}
 
+	return newIdx(num, lim)
+}
+
+// Constructs index from candidate number
+func newIdx(num int, lim int) (SelectIdx, error) {
 	// Calculate index
 	idx := num - 1
 
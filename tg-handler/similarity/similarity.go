@@ -0,0 +1,110 @@
+package similarity
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Gets similarity of texts from 0 (different) to 1 (same)
+// as normalized Levenshtein distance of words ignoring case
+// and punctuation
+func Ratio(a, b string) float64 {
+	wa, wb := words(a), words(b)
+
+	longest := max(len(wa), len(wb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(distance(wa, wb))/float64(longest)
+}
+
+// Splits text into lower case words
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Gets Levenshtein distance of word sequences
+func distance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range a {
+		curr[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			curr[j+1] = min(
+				prev[j+1]+1,  // Deletion
+				curr[j]+1,    // Insertion
+				prev[j]+cost, // Substitution
+			)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// Gets share of text word n-grams found in lines,
+// n shrinks to text length for short texts
+func NGramOverlap(text string, lines []string, n int) float64 {
+	tw := words(text)
+	n = min(n, len(tw))
+	if n == 0 {
+		return 0
+	}
+
+	// Collect line n-grams
+	seen := make(map[string]struct{})
+	for _, line := range lines {
+		for _, gram := range nGrams(words(line), n) {
+			seen[gram] = struct{}{}
+		}
+	}
+
+	// Count text n-grams seen
+	grams := nGrams(tw, n)
+	var found int
+	for _, gram := range grams {
+		if _, ok := seen[gram]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(grams))
+}
+
+// Gets word n-grams joined by space
+func nGrams(ws []string, n int) []string {
+	if len(ws) < n {
+		return nil
+	}
+
+	grams := make([]string, 0, len(ws)-n+1)
+	for i := range len(ws) - n + 1 {
+		grams = append(grams, strings.Join(ws[i:i+n], " "))
+	}
+	return grams
+}
+
+// Gets cosine similarity of vectors, 0 if any is zero
+func Cosine(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,73 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// OpenAI compatible speech path
+const speechPath = "/v1/audio/speech"
+
+// OpenAI compatible speech server synthesizer
+type openAISpeech struct {
+	endpoint string // Base URL
+	model    string // Server default if empty
+	client   *http.Client
+}
+
+// OpenAI compatible speech request
+type speechRequest struct {
+	Model          string `json:"model,omitempty"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// Synthesizes text with one speech request,
+// Opus is what Telegram plays as voice note
+func (o *openAISpeech) Synthesize(
+	ctx context.Context, text, voice string,
+) ([]byte, error) {
+	// Encode request body to JSON data
+	jsonData, err := json.Marshal(speechRequest{
+		Model:          o.model,
+		Input:          text,
+		Voice:          voice,
+		ResponseFormat: "opus",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+
+	// Send request
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, o.endpoint+speechPath,
+		bytes.NewReader(jsonData),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", errBadStatus, resp.Status)
+	}
+
+	// Read audio
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDecodeFailed, err)
+	}
+	if len(audio) == 0 {
+		return nil, errEmptyResult
+	}
+	return audio, nil
+}
@@ -0,0 +1,68 @@
+package speech
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"tg-handler/conf"
+)
+
+// Speech defaults
+const defaultTimeout = 2 * time.Minute
+
+// Speech errors
+var (
+	errRequestFailed = errors.New("speech request failed")
+	errBadStatus     = errors.New("speech server responded with error")
+	errDecodeFailed  = errors.New("failed to decode speech response")
+	errEmptyResult   = errors.New("empty speech result")
+)
+
+// Speech-to-text backend
+type Transcriber interface {
+	// Transcribes audio file data named with its extension
+	Transcribe(ctx context.Context, audio []byte, name string) (string, error)
+}
+
+// Text-to-speech backend
+type Synthesizer interface {
+	// Synthesizes text in voice as OGG/Opus audio
+	Synthesize(ctx context.Context, text, voice string) ([]byte, error)
+}
+
+// Constructs transcriber, nil if endpoint is not set
+func NewTranscriber(settings *conf.TranscriptionSettings) Transcriber {
+	if settings.Endpoint == "" {
+		return nil
+	}
+
+	return &whisper{
+		endpoint: strings.TrimSuffix(settings.Endpoint, "/"),
+		language: settings.Language,
+		client:   newClient(settings.Timeout),
+	}
+}
+
+// Constructs synthesizer, nil if endpoint is not set
+func NewSynthesizer(settings *conf.SynthesisSettings) Synthesizer {
+	if settings.Endpoint == "" {
+		return nil
+	}
+
+	return &openAISpeech{
+		endpoint: strings.TrimSuffix(settings.Endpoint, "/"),
+		model:    settings.Model,
+		client:   newClient(settings.Timeout),
+	}
+}
+
+// Constructs HTTP client with timeout, default one if not set
+func newClient(timeout conf.Duration) *http.Client {
+	if timeout <= 0 {
+		return &http.Client{Timeout: defaultTimeout}
+	}
+	return &http.Client{Timeout: time.Duration(timeout)}
+}
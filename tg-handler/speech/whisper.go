@@ -0,0 +1,89 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// whisper.cpp server inference path
+const inferencePath = "/inference"
+
+// whisper.cpp server compatible transcriber,
+// server should convert audio (--convert) as voice notes are OGG/Opus
+type whisper struct {
+	endpoint string // Base URL
+	language string // Auto if empty
+	client   *http.Client
+}
+
+// whisper.cpp server response
+type whisperResponse struct {
+	Text string `json:"text"`
+}
+
+// Transcribes audio with one inference request
+func (w *whisper) Transcribe(
+	ctx context.Context, audio []byte, name string,
+) (string, error) {
+	// Encode multipart form
+	var (
+		body   bytes.Buffer
+		writer = multipart.NewWriter(&body)
+	)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	fields := [][2]string{
+		{"response_format", "json"},
+		{"temperature", "0"},
+		{"language", w.language},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+
+	// Send request
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, w.endpoint+inferencePath, &body,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errRequestFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s", errBadStatus, resp.Status)
+	}
+
+	// Decode transcript
+	var response whisperResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("%w: %v", errDecodeFailed, err)
+	}
+	text := strings.Join(strings.Fields(response.Text), " ")
+	if text == "" {
+		return "", errEmptyResult
+	}
+	return text, nil
+}
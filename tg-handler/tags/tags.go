@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"tg-handler/logging"
+	"tg-handler/schema"
 )
 
 // Tags errors
@@ -24,6 +25,43 @@ var (
 
 type Tags []tag
 
+// Structured tags output
+type Output struct {
+	Tags []string `json:"tags"`
+}
+
+// Schema for structured tags output
+var Schema = schema.MustOf(Output{})
+
+// Parses structured output, falls back to free text
+func Parse(s string, lim int, logger *logging.Logger) (Tags, error) {
+	var output Output
+	if err := schema.Decode(s, &output); err != nil {
+		return New(s, lim, logger)
+	}
+	return FromList(output.Tags, lim, logger)
+}
+
+// Accumulates unique tags from structured list
+func FromList(
+	list []string, lim int, logger *logging.Logger,
+) (Tags, error) {
+	// Normalize raw tags: one word with '#' prefix
+	rawTags := make([]string, 0, len(list))
+	for _, rawTag := range list {
+		rawTag = strings.Join(strings.Fields(rawTag), "_")
+		if rawTag == "" {
+			continue
+		}
+		if !strings.HasPrefix(rawTag, "#") {
+			rawTag = "#" + rawTag
+		}
+		rawTags = append(rawTags, rawTag)
+	}
+
+	return accumulate(rawTags, lim, logger)
+}
+
 // Parses string from LLM and accumulates unique tags from it
 func New(s string, lim int, logger *logging.Logger) (Tags, error) {
 	// Handle empty string
@@ -31,11 +69,18 @@ func New(s string, lim int, logger *logging.Logger) (Tags, error) {
 		return nil, errEmptyRawTagsString
 	}
 
-	var tags []tag
-
 	// Get raw tags
 	rawTags := strings.Fields(s)
 
+	return accumulate(rawTags, lim, logger)
+}
+
+// Accumulates unique tags from raw tags up to limit
+func accumulate(
+	rawTags []string, lim int, logger *logging.Logger,
+) (Tags, error) {
+	var tags []tag
+
 	// Accumulate unique tags
 	seen := make(map[tag]bool)
 	for _, rawTag := range rawTags {
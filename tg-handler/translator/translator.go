@@ -1,27 +1,159 @@
 package translator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
-	"github.com/bregydoc/gtranslate"
+	"tg-handler/conf"
+	"tg-handler/logging"
+)
+
+// Google Translate endpoint and request timeout
+const (
+	googleURL     = "https://translate.google.com/translate_a/single"
+	googleTimeout = 10 * time.Second
 )
 
 var (
-	errMsgTranslationFailed = errors.New(
-		"failed to translate from English",
-	)
+	errMsgTranslationFailed = errors.New("failed to translate")
+	errUnknownTranslator    = errors.New("unknown translator")
+	errBadResponse          = errors.New("unexpected translation response")
 )
 
-// Translates message to language based on its tag
-func Translate(msg string) (string, error) {
-	msg, err := gtranslate.TranslateWithParams(msg, gtranslate.TranslationParams{
-		From: "en",
-		To:   "eo",
-	})
+// Translates text between languages by their codes
+type Translator interface {
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// Translator sending text as is
+type None struct{}
+
+func (None) Translate(
+	_ context.Context, text, _, _ string,
+) (string, error) {
+	return text, nil
+}
+
+// Translator with Google Translate
+type Google struct{}
+
+// Translates with request bound to context and timeout
+func (Google) Translate(
+	ctx context.Context, text, from, to string,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, googleTimeout)
+	defer cancel()
+
+	// Form request
+	params := url.Values{
+		"client": {"gtx"},
+		"sl":     {from},
+		"tl":     {to},
+		"dt":     {"t"},
+		"q":      {text},
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, googleURL+"?"+params.Encode(), nil,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// Send request
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s", errBadResponse, resp.Status)
+	}
+
+	// Decode sentences: [[["translated", "original", ...], ...], ...]
+	var body []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %v", errBadResponse, err)
+	}
+	if len(body) == 0 {
+		return "", errBadResponse
+	}
+	var sentences [][]any
+	if err := json.Unmarshal(body[0], &sentences); err != nil {
+		return "", fmt.Errorf("%w: %v", errBadResponse, err)
+	}
+
+	var sb strings.Builder
+	for _, sentence := range sentences {
+		if len(sentence) == 0 {
+			continue
+		}
+		if s, ok := sentence[0].(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String(), nil
+}
+
+// Translators of bot picked per chat
+type Translators struct {
+	settings *conf.TranslationSettings
+	byName   map[string]Translator
+}
+
+// Constructs bot translators with model backend one
+func New(
+	settings *conf.TranslationSettings, llm Translator,
+) *Translators {
+	return &Translators{
+		settings: settings,
+		byName: map[string]Translator{
+			conf.TranslatorNone:   None{},
+			conf.TranslatorLLM:    llm,
+			conf.TranslatorGoogle: Google{},
+		},
+	}
+}
+
+// Translates text for chat to target language, chat one if empty;
+// returns it as is on failure so that replies are sent offline
+func (ts *Translators) Translate(
+	ctx context.Context,
+	chatID int64,
+	text string,
+	to string,
+	logger *logging.Logger,
+) string {
+	const errMsg = "sending untranslated text"
+
+	chat := ts.settings.For(chatID)
+	if to != "" {
+		chat.To = to
+	}
+	if chat.Translator == conf.TranslatorNone || chat.From == chat.To {
+		return text
+	}
+
+	// Get translator
+	translator, ok := ts.byName[chat.Translator]
+	if !ok {
+		logger.Error(errMsg, logging.Err(errUnknownTranslator))
+		return text
+	}
+
+	// Translate
+	translated, err := translator.Translate(ctx, text, chat.From, chat.To)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", errMsgTranslationFailed, err)
+		logger.Error(errMsg, logging.Err(
+			fmt.Errorf("%w: %v", errMsgTranslationFailed, err),
+		))
+		return text
 	}
 
-	return msg, nil
+	return translated
 }